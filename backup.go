@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The built-in patterns of the backup and temporary files left over by editors, archivers and admins.
+// {file} is the file name, {name} and {ext} its name and extension, {hidden} the file name with a leading dot.
+var defaultBackupPatterns = []string{
+	"{file}~",
+	"{file}.bak",
+	"{file}.bk",
+	"{file}.bkp",
+	"{file}.backup",
+	"{file}.old",
+	"{file}.orig",
+	"{file}.save",
+	"{file}.sav",
+	"{file}.swp",
+	"{file}.tmp",
+	"{file}.copy",
+	"{file}.1",
+	"{file}_bak",
+	"{file}_old",
+	"{hidden}.swp",
+	"{hidden}.swo",
+	"{hidden}.un~",
+	"#{file}#",
+	".#{file}",
+	"{name}.old.{ext}",
+	"{name}.bak.{ext}",
+	"{name}_old.{ext}",
+	"{name}_bak.{ext}",
+	"{name}_backup.{ext}",
+	"{name}-copy.{ext}",
+	"{name}1.{ext}",
+}
+
+var backupPlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// backupPattern builds a variant out of the name and extension of a file
+type backupPattern struct {
+	text string
+	// patterns using {ext} only apply to the files with an extension
+	needsExt bool
+}
+
+// Parse a backup pattern, which must use one of the {file}, {name} or {hidden} placeholders
+func parseBackupPattern(text string) (backupPattern, error) {
+	p := backupPattern{text: text}
+	var named bool
+	for _, placeholder := range backupPlaceholder.FindAllString(text, -1) {
+		switch placeholder {
+		case "{file}", "{name}", "{hidden}":
+			named = true
+		case "{ext}":
+			p.needsExt = true
+		default:
+			return p, fmt.Errorf("unknown placeholder %s", placeholder)
+		}
+	}
+	if !named {
+		return p, fmt.Errorf("the pattern needs one of {file}, {name} or {hidden}")
+	}
+	return p, nil
+}
+
+// Build the variant of a file name, returning false when the pattern does not apply to it
+func (p backupPattern) apply(file string) (string, bool) {
+	name, ext := file, ""
+	if i := strings.LastIndex(file, "."); i > 0 {
+		name, ext = file[:i], file[i+1:]
+	}
+	if p.needsExt && ext == "" {
+		return "", false
+	}
+	hidden := file
+	if !strings.HasPrefix(hidden, ".") {
+		hidden = "." + hidden
+	}
+	return strings.NewReplacer("{file}", file, "{name}", name, "{ext}", ext, "{hidden}", hidden).Replace(p.text), true
+}
+
+// Load the built-in backup patterns, followed by the ones of a file, or the standard input for "-", one per line.
+// Blank lines and lines starting with # followed by a space are skipped.
+func loadBackupPatterns(filename string) ([]backupPattern, error) {
+	var patterns []backupPattern
+	for _, text := range defaultBackupPatterns {
+		p, err := parseBackupPattern(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid built-in backup pattern %q: %v", text, err)
+		}
+		patterns = append(patterns, p)
+	}
+	if filename == "" {
+		return patterns, nil
+	}
+
+	file, err := openInput(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		// unlike comments, the #{file}# pattern has no space after the #
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text == "#" || strings.HasPrefix(text, "# ") {
+			continue
+		}
+		p, err := parseBackupPattern(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid backup pattern %q: %v", filename, line, text, err)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, scanner.Err()
+}
+
+// Build the backup variants of a word, preceded by the word itself.
+// Only the file like words, the ones with a dot in their last path segment, get variants.
+func backupVariants(word string, patterns []backupPattern) []string {
+	if word == "" || isBareWord(word) || strings.HasSuffix(word, "/") {
+		return []string{word}
+	}
+	i := strings.LastIndex(word, "/") + 1
+	dir, file := word[:i], word[i:]
+	variants := make([]string, 0, len(patterns)+1)
+	variants = append(variants, word)
+	for _, p := range patterns {
+		if variant, ok := p.apply(file); ok {
+			variants = append(variants, dir+variant)
+		}
+	}
+	return variants
+}
+
+// backupExpansion follows a word with its backup variants
+func backupExpansion(patterns []backupPattern) func(string) []string {
+	return func(word string) []string {
+		return backupVariants(word, patterns)
+	}
+}
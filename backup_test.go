@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBackupPattern(t *testing.T) {
+	p, err := parseBackupPattern("{name}.old.{ext}")
+	assert.NoError(t, err)
+	assert.True(t, p.needsExt)
+
+	_, err = parseBackupPattern("{ext}.bak")
+	assert.EqualError(t, err, "the pattern needs one of {file}, {name} or {hidden}")
+	_, err = parseBackupPattern("{file}.{extension}")
+	assert.EqualError(t, err, "unknown placeholder {extension}")
+}
+
+func TestBackupVariants(t *testing.T) {
+	var patterns []backupPattern
+	for _, text := range []string{"{file}~", "{hidden}.swp", "{file}.bak", "{file}.orig", "#{file}#", "{name}.old.{ext}"} {
+		p, err := parseBackupPattern(text)
+		assert.NoError(t, err)
+		patterns = append(patterns, p)
+	}
+
+	assert.Equal(t, []string{"config.php", "config.php~", ".config.php.swp", "config.php.bak", "config.php.orig", "#config.php#", "config.old.php"}, backupVariants("config.php", patterns))
+	assert.Equal(t, []string{"inc/db.inc.php", "inc/db.inc.php~", "inc/.db.inc.php.swp", "inc/db.inc.php.bak", "inc/db.inc.php.orig", "inc/#db.inc.php#", "inc/db.inc.old.php"}, backupVariants("inc/db.inc.php", patterns))
+	// dot files have no extension and keep their single leading dot
+	assert.Equal(t, []string{".htaccess", ".htaccess~", ".htaccess.swp", ".htaccess.bak", ".htaccess.orig", "#.htaccess#"}, backupVariants(".htaccess", patterns))
+	assert.Equal(t, []string{"admin"}, backupVariants("admin", patterns))
+	assert.Equal(t, []string{"v1.2/"}, backupVariants("v1.2/", patterns))
+}
+
+func TestLoadBackupPatterns(t *testing.T) {
+	patterns, err := loadBackupPatterns("")
+	assert.NoError(t, err)
+	assert.Len(t, patterns, len(defaultBackupPatterns))
+
+	filename := filepath.Join(t.TempDir(), "patterns.txt")
+	assert.NoError(t, os.WriteFile(filename, []byte("# site specific\n\n{name}.{ext}.2019\n#{file}.bak#\n"), 0644))
+	patterns, err = loadBackupPatterns(filename)
+	assert.NoError(t, err)
+	if assert.Len(t, patterns, len(defaultBackupPatterns)+2) {
+		assert.Equal(t, "#{file}.bak#", patterns[len(patterns)-1].text)
+	}
+
+	assert.NoError(t, os.WriteFile(filename, []byte("{file}.bak\n{dir}/{file}\n"), 0644))
+	_, err = loadBackupPatterns(filename)
+	assert.EqualError(t, err, filename+`:2: invalid backup pattern "{dir}/{file}": unknown placeholder {dir}`)
+}
+
+func TestSearchSubcmdBackupVariants(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "php", "config", "index.php"})
+
+	assert.NoError(t, searchSubcmd([]string{"-tags", "php", "-ext", "php", "-backup-variants"}))
+	// config is kept as it is, config.php and index.php are followed by their variants
+	n := len(defaultBackupPatterns) + 1
+	assert.Contains(t, buf.String(), fmt.Sprintf("[expandWriter] generated %d candidates, 0 duplicates dropped", 2*n+1))
+	assert.Error(t, searchSubcmd([]string{"-tags", "php", "-backup-patterns", "missing.txt"}))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/proditis/orunmila/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// parse args of the batches subcommand and exec the requested action
+func batchesSubcmd(args []string) error {
+	batchesCmd := flag.NewFlagSet("batches", flag.ContinueOnError)
+
+	batchesCmd.SetOutput(flag.CommandLine.Output())
+
+	batchesCmd.Usage = func() {
+		fmt.Fprint(batchesCmd.Output(), "List and undo the import and add runs of the database\n\n")
+		fmt.Fprintln(batchesCmd.Output(), "Usage of orunmila batches:")
+		fmt.Fprintln(batchesCmd.Output(), "orunmila [-db <db_path>] [-debug] batches <action> [arguments]")
+		fmt.Fprintln(batchesCmd.Output(), "\nActions")
+		fmt.Fprintln(batchesCmd.Output(), "  ls                              List the batches along with the words and associations they introduced")
+		fmt.Fprintln(batchesCmd.Output(), "  undo <ids>...                   Remove the words and associations introduced by batches")
+	}
+
+	return runAction(batchesCmd, args, []subcmdAction{
+		{names: []string{"ls", "list"}, run: batchesLs},
+		{names: []string{"undo"}, run: batchesUndo},
+	})
+}
+
+// list the batches along with what they introduced
+func batchesLs(args []string) error {
+	cmd := newActionFlagSet("batches", "ls", "ls", "List the batches along with the words and associations they introduced")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+
+	db := openStoreReadOnly()
+	defer db.Close()
+
+	batches, err := db.Batches()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tWORDS\tLINKS\tHITS\tCOMMAND")
+	for _, batch := range batches {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%s\n", batch.ID, batch.CreatedAt.Format("2006-01-02 15:04:05"), batch.Words, batch.Links, batch.Hits, batch.Command)
+	}
+	return w.Flush()
+}
+
+// remove the words and associations introduced by batches
+func batchesUndo(args []string) error {
+	cmd := newActionFlagSet("batches", "undo", "undo <ids>...", "Remove the words and associations introduced by batches, the ones that existed before are kept")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	if cmd.NArg() == 0 {
+		cmd.Usage()
+		return fmt.Errorf("undo needs at least one batch id")
+	}
+
+	var ids []int64
+	for _, arg := range cmd.Args() {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid batch id %q", arg)
+		}
+		ids = append(ids, id)
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	for _, id := range ids {
+		if err := undoBatch(db, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Undo a single batch and log what it removed
+func undoBatch(db *store.Store, id int64) error {
+	batch, err := db.UndoBatch(id)
+	if err != nil {
+		return err
+	}
+	log.Infof("[undoBatch] undid batch %d (%s), removed %d words, %d associations and %d hits", batch.ID, strings.TrimSpace(batch.Command), batch.Words, batch.Links, batch.Hits)
+	return nil
+}
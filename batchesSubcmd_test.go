@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchesSubcmdActions(t *testing.T) {
+	assert.EqualError(t, batchesSubcmd([]string{"undo", "first"}), `invalid batch id "first"`)
+
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+
+	addSubcmd([]string{"-tags", "cli", "common1"})
+	importSubcmd([]string{"-tags", "lista", "lista.txt"})
+
+	assert.NoError(t, batchesSubcmd([]string{"ls"}))
+	assert.NoError(t, batchesSubcmd([]string{"undo", "2"}))
+	assert.Error(t, batchesSubcmd([]string{"undo", "2"}))
+
+	db := openStore()
+	defer db.Close()
+	var words, links int64
+	assert.NoError(t, db.DB().QueryRow("select count(*) from words").Scan(&words))
+	assert.NoError(t, db.DB().QueryRow("select count(*) from wt").Scan(&links))
+	assert.Equal(t, int64(1), words)
+	assert.Equal(t, int64(1), links)
+}
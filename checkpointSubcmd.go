@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// parse args of the checkpoint subcommand and exec the requested action
+func checkpointSubcmd(args []string) error {
+	checkpointCmd := flag.NewFlagSet("checkpoint", flag.ContinueOnError)
+
+	checkpointCmd.SetOutput(flag.CommandLine.Output())
+
+	checkpointCmd.Usage = func() {
+		fmt.Fprint(checkpointCmd.Output(), "Manage the checkpoints of search -checkpoint\n\n")
+		fmt.Fprintln(checkpointCmd.Output(), "Usage of orunmila checkpoint:")
+		fmt.Fprintln(checkpointCmd.Output(), "orunmila [-db <db_path>] [-debug] checkpoint <action> [arguments]")
+		fmt.Fprintln(checkpointCmd.Output(), "\nActions")
+		fmt.Fprintln(checkpointCmd.Output(), "  ls                              List the checkpoints along with the number of words they returned")
+		fmt.Fprintln(checkpointCmd.Output(), "  reset <names>...                Forget the words returned under checkpoints")
+	}
+
+	return runAction(checkpointCmd, args, []subcmdAction{
+		{names: []string{"ls", "list"}, run: checkpointLs},
+		{names: []string{"reset", "rm"}, run: checkpointReset},
+	})
+}
+
+// list the checkpoints along with their word counts
+func checkpointLs(args []string) error {
+	cmd := newActionFlagSet("checkpoint", "ls", "ls", "List the checkpoints along with the number of words they returned")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+
+	db := openStoreReadOnly()
+	defer db.Close()
+
+	checkpoints, err := db.Checkpoints()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECKPOINT\tWORDS\tCREATED\tLAST USED")
+	for _, checkpoint := range checkpoints {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", checkpoint.Name, checkpoint.Words, checkpoint.CreatedAt.Format("2006-01-02 15:04:05"), checkpoint.UpdatedAt.Format("2006-01-02 15:04:05"))
+	}
+	return w.Flush()
+}
+
+// forget the words returned under checkpoints
+func checkpointReset(args []string) error {
+	cmd := newActionFlagSet("checkpoint", "reset", "reset <names>...", "Forget the words returned under checkpoints, their next search returns every word again")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	if cmd.NArg() == 0 {
+		cmd.Usage()
+		return fmt.Errorf("reset needs at least one checkpoint")
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	for _, name := range cmd.Args() {
+		words, err := db.ResetCheckpoint(name)
+		if err != nil {
+			return err
+		}
+		log.Infof("[checkpointReset] reset checkpoint %q, %d words forgotten", name, words)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpointSubcmdActions(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+
+	importSubcmd([]string{"-tags", "lista", "lista.txt"})
+	assert.NoError(t, searchSubcmd([]string{"-tags", "lista", "-checkpoint", "programXYZ"}))
+	assert.NoError(t, checkpointSubcmd([]string{"ls"}))
+
+	db := openStore()
+	checkpoints, err := db.Checkpoints()
+	db.Close()
+	assert.NoError(t, err)
+	if assert.Len(t, checkpoints, 1) {
+		assert.Equal(t, "programXYZ", checkpoints[0].Name)
+		assert.Equal(t, int64(4), checkpoints[0].Words)
+	}
+
+	assert.NoError(t, checkpointSubcmd([]string{"reset", "programXYZ"}))
+	assert.Error(t, checkpointSubcmd([]string{"reset", "programXYZ"}))
+}
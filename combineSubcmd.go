@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/proditis/orunmila/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// parse args of the combine subcommand and exec it
+func combineSubcmd(args []string) error {
+	combineCmd := flag.NewFlagSet("combine", flag.ContinueOnError)
+
+	combineCmd.SetOutput(flag.CommandLine.Output())
+
+	combineCmd.Usage = func() {
+		fmt.Fprint(combineCmd.Output(), "Display the cross product of lists of words, joined by a separator\n\n")
+		fmt.Fprintln(combineCmd.Output(), "Usage of orunmila combine:")
+		fmt.Fprintln(combineCmd.Output(), "orunmila [-db <db_path>] [-debug] combine [-sep SEP] [-max N] [-dedupe] -left SIDE [-side SIDE]... -right SIDE")
+		combineCmd.PrintDefaults()
+		fmt.Fprintln(combineCmd.Output(), "\texample: orunmila combine -left 'tags:api-prefix' -right 'tags:endpoints' -sep /")
+		fmt.Fprintln(combineCmd.Output(), "\texample: orunmila combine -left 'words:api,v1,v2' -side 'tags:endpoints' -right 'words:list,create' -sep /")
+		fmt.Fprintln(combineCmd.Output(), "\texample: orunmila combine -left 'tags:prefixes' -right 'q:subdomains and not internal' -sep - -max 100000")
+	}
+
+	var specs combineSpecs
+	combineCmd.Var(&specs, "side", "a middle side of the product, tags:LIST, q:QUERY or words:LIST, repeated for every side between -left and -right in order")
+	var (
+		leftPtr   = combineCmd.String("left", "", "the first side of the product, tags:LIST, q:QUERY or words:LIST")
+		rightPtr  = combineCmd.String("right", "", "the last side of the product, tags:LIST, q:QUERY or words:LIST")
+		sepPtr    = combineCmd.String("sep", "", "the separator between the words of the sides")
+		maxPtr    = combineCmd.Int64("max", 0, "stop after writing this many combinations (default: no limit)")
+		dedupePtr = combineCmd.Bool("dedupe", false, "drop the duplicate combinations, keeping every combination in memory")
+	)
+
+	err := combineCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	log.Debugln("[combineSubcmd] using db:", *dbPtr)
+	log.Debugln("[combineSubcmd] left:", *leftPtr, "sides:", specs.String(), "right:", *rightPtr)
+	log.Debugln("[combineSubcmd] sep:", *sepPtr, "max:", *maxPtr, "dedupe:", *dedupePtr)
+
+	if combineCmd.NArg() > 0 {
+		combineCmd.Usage()
+		err = fmt.Errorf("combine takes its sides with -left, -side and -right, not as arguments: %q", combineCmd.Args())
+		log.Errorln("[combineSubcmd]", err)
+		return err
+	}
+	// -left comes first and -right last, the -side flags in between
+	if *leftPtr != "" {
+		specs = append(combineSpecs{*leftPtr}, specs...)
+	}
+	if *rightPtr != "" {
+		specs = append(specs, *rightPtr)
+	}
+	if len(specs) < 2 {
+		combineCmd.Usage()
+		err = fmt.Errorf("combine needs at least two sides")
+		log.Errorln("[combineSubcmd]", err)
+		return err
+	}
+
+	sides := make([]combineSide, len(specs))
+	for i, spec := range specs {
+		if sides[i], err = parseCombineSide(spec); err != nil {
+			log.Errorln("[combineSubcmd]", err)
+			return err
+		}
+	}
+
+	db := openStoreReadOnly()
+	defer db.Close()
+
+	total := int64(1)
+	for _, side := range sides {
+		n, err := side.count(db)
+		if err != nil {
+			log.Errorln("[combineSubcmd]", err)
+			return err
+		}
+		total *= n
+	}
+	log.Infof("[combineSubcmd] %d sides, up to %d combinations", len(sides), total)
+
+	written, err := combineWords(db, sides, combineOptions{sep: *sepPtr, max: *maxPtr, dedupe: *dedupePtr}, os.Stdout)
+	if err != nil {
+		log.Errorln("[combineSubcmd]", err)
+		return err
+	}
+	log.Infof("[combineSubcmd] wrote %d combinations", written)
+	return nil
+}
+
+// combineSpecs collects the repeated -side flags of combine, the middle sides in order
+type combineSpecs []string
+
+func (c *combineSpecs) String() string {
+	return strings.Join(*c, " ")
+}
+
+func (c *combineSpecs) Set(spec string) error {
+	*c = append(*c, spec)
+	return nil
+}
+
+// combineSide is a list of words to combine, either literal or the result of a tag query
+type combineSide struct {
+	words []string
+	query store.Expr
+}
+
+// Parse a side of combine: tags:LIST for the words of any of the tags, q:QUERY for the words
+// matching a tag query or words:LIST for a literal comma separated list
+func parseCombineSide(spec string) (combineSide, error) {
+	var side combineSide
+	kind, value, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "tags":
+		side.query = store.TagsQuery(splitList(value))
+	case "q":
+		if strings.TrimSpace(value) != "" {
+			query, err := store.ParseQuery(value)
+			if err != nil {
+				return side, fmt.Errorf("invalid side %q: %v", spec, err)
+			}
+			side.query = query
+		}
+	case "words":
+		side.words = splitList(value)
+		if len(side.words) == 0 {
+			return side, fmt.Errorf("invalid side %q: no words given", spec)
+		}
+		return side, nil
+	default:
+		return side, fmt.Errorf("invalid side %q, expected tags:LIST, q:QUERY or words:LIST", spec)
+	}
+	if side.query == nil {
+		return side, fmt.Errorf("invalid side %q: no tags given", spec)
+	}
+	return side, nil
+}
+
+// Call fn with every word of the side, streaming them from the database
+func (c combineSide) each(db *store.Store, fn func(string) error) error {
+	if c.query == nil {
+		for _, word := range c.words {
+			if err := fn(word); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return db.Search(store.SearchOptions{Query: c.query}, func(rec store.Record) error {
+		return fn(rec.Word)
+	})
+}
+
+// Count the words of the side
+func (c combineSide) count(db *store.Store) (int64, error) {
+	if c.query == nil {
+		return int64(len(c.words)), nil
+	}
+	return db.Count(store.SearchOptions{Query: c.query})
+}
+
+// combineOptions control how combineWords joins and writes the combinations
+type combineOptions struct {
+	sep string
+	// max stops after that many combinations, 0 for no limit
+	max    int64
+	dedupe bool
+}
+
+// errCombineDone stops the combination once -max combinations were written
+var errCombineDone = errors.New("done")
+
+// Write the cross product of the sides to w, one combination per line. The first side is streamed
+// once and every other side is streamed again for each prefix, so that neither the sides nor the
+// product are held in memory. It returns the number of combinations written.
+func combineWords(db *store.Store, sides []combineSide, opts combineOptions, w io.Writer) (int64, error) {
+	out := bufio.NewWriter(w)
+	var seen candidateSet
+	if opts.dedupe {
+		seen = make(candidateSet)
+	}
+
+	var written int64
+	var walk func(i int, prefix string) error
+	walk = func(i int, prefix string) error {
+		if i == len(sides) {
+			if seen != nil && !seen.add(prefix) {
+				return nil
+			}
+			if _, err := fmt.Fprintln(out, prefix); err != nil {
+				return err
+			}
+			if written++; opts.max > 0 && written >= opts.max {
+				return errCombineDone
+			}
+			return nil
+		}
+		return sides[i].each(db, func(word string) error {
+			if i > 0 {
+				word = prefix + opts.sep + word
+			}
+			return walk(i+1, word)
+		})
+	}
+
+	err := walk(0, "")
+	if errors.Is(err, errCombineDone) {
+		log.Infof("[combineWords] stopped after %d combinations", written)
+		err = nil
+	}
+	if err != nil {
+		return written, err
+	}
+	return written, out.Flush()
+}
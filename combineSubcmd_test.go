@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCombineSide(t *testing.T) {
+	side, err := parseCombineSide("words:api, v1,v2,api")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api", "v1", "v2"}, side.words)
+	side, err = parseCombineSide("tags:a,b")
+	assert.NoError(t, err)
+	assert.Equal(t, "(a or b)", side.query.String())
+	side, err = parseCombineSide("q:a and not b")
+	assert.NoError(t, err)
+	assert.Equal(t, "(a and not b)", side.query.String())
+
+	for spec, msg := range map[string]string{
+		"a,b":     `invalid side "a,b", expected tags:LIST, q:QUERY or words:LIST`,
+		"words:,": `invalid side "words:,": no words given`,
+		"tags:":   `invalid side "tags:": no tags given`,
+		"q: ":     `invalid side "q: ": no tags given`,
+	} {
+		_, err = parseCombineSide(spec)
+		assert.EqualError(t, err, msg)
+	}
+	_, err = parseCombineSide("q:a and")
+	assert.Error(t, err)
+}
+
+func TestCombineWords(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "endpoints", "users", "admin"})
+
+	db := openStoreReadOnly()
+	defer db.Close()
+
+	combine := func(opts combineOptions, specs ...string) (string, int64) {
+		var sides []combineSide
+		for _, spec := range specs {
+			side, err := parseCombineSide(spec)
+			assert.NoError(t, err)
+			sides = append(sides, side)
+		}
+		var out bytes.Buffer
+		n, err := combineWords(db, sides, opts, &out)
+		assert.NoError(t, err)
+		return out.String(), n
+	}
+
+	out, n := combine(combineOptions{sep: "/"}, "words:api,v1", "tags:endpoints")
+	assert.Equal(t, "api/users\napi/admin\nv1/users\nv1/admin\n", out)
+	assert.Equal(t, int64(4), n)
+
+	out, _ = combine(combineOptions{sep: "-"}, "words:a", "tags:endpoints", "words:1,2")
+	assert.Equal(t, "a-users-1\na-users-2\na-admin-1\na-admin-2\n", out)
+
+	out, n = combine(combineOptions{max: 3}, "words:a,b", "words:a,b")
+	assert.Equal(t, "aa\nab\nba\n", out)
+	assert.Equal(t, int64(3), n)
+
+	out, n = combine(combineOptions{dedupe: true}, "words:a,ab", "words:b,bb,")
+	assert.Equal(t, "ab\nabb\nabbb\n", out)
+	assert.Equal(t, int64(3), n)
+}
+
+func TestCombineSubcmd(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "endpoints", "users", "admin"})
+
+	assert.Equal(t, flag.ErrHelp, combineSubcmd([]string{"-help"}))
+	assert.EqualError(t, combineSubcmd([]string{"-side", "words:a"}), "combine needs at least two sides")
+	assert.Error(t, combineSubcmd([]string{"-side", "words:a", "-side", "tags:missing"}))
+	assert.EqualError(t, combineSubcmd([]string{"-left", "words:a"}), "combine needs at least two sides")
+	assert.EqualError(t, combineSubcmd([]string{"-side", "words:a", "words:b"}), `combine takes its sides with -left, -side and -right, not as arguments: ["words:b"]`)
+
+	// the output of combineSubcmd written to stdout
+	combine := func(args ...string) string {
+		r, w, err := os.Pipe()
+		assert.NoError(t, err)
+		stdout := os.Stdout
+		os.Stdout = w
+		defer func() { os.Stdout = stdout }()
+
+		err = combineSubcmd(args)
+		os.Stdout = stdout
+		w.Close()
+		assert.NoError(t, err)
+		out, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		return string(out)
+	}
+
+	addSubcmd([]string{"-tags", "api-prefix", "api", "v1"})
+	out := combine("-left", "tags:api-prefix", "-right", "tags:endpoints", "-sep", "/")
+	assert.Equal(t, "api/users\napi/admin\nv1/users\nv1/admin\n", out)
+
+	// -left comes first and -right last, whatever the order of the flags, the -side flags in between in order
+	buf.Reset()
+	out = combine("-right", "words:x", "-side", "words:api,v1", "-sep", "/", "-side", "tags:endpoints", "-max", "3", "-left", "words:a")
+	assert.Equal(t, "a/api/users/x\na/api/admin/x\na/v1/users/x\n", out)
+	assert.Contains(t, buf.String(), "4 sides, up to 4 combinations")
+
+	out = combine("-side", "words:api,v1", "-sep", "/", "-side", "tags:endpoints", "-max", "3", "-side", "words:x")
+	assert.Equal(t, "api/users/x\napi/admin/x\nv1/users/x\n", out)
+}
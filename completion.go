@@ -0,0 +1,401 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/proditis/orunmila/store"
+)
+
+// completeCmdName is the hidden subcommand the completion scripts call to complete a command line
+const completeCmdName = "__complete"
+
+// valueKind tells how the value of a flag or a positional argument is completed
+type valueKind int
+
+const (
+	valueNone       valueKind = iota // left to the shell, which completes filenames
+	valueBool                        // a boolean flag, it takes no value
+	valueTags                        // a comma separated list of tags of the database
+	valueTag                         // a single tag of the database
+	valueExtSet                      // the name of an extension set
+	valueCheckpoint                  // the name of a checkpoint
+)
+
+// completionFlag is a flag of a subcommand, the values of the flags with choices are completed from them
+type completionFlag struct {
+	name    string
+	kind    valueKind
+	choices []string
+}
+
+// completionCmd describes a subcommand, or an action of one, for the completion
+type completionCmd struct {
+	names   []string // the name then the aliases
+	flags   []completionFlag
+	actions []*completionCmd
+	arg     completionFlag // how the positional arguments are completed
+	nargs   int            // the number of positional arguments completed, 0 for all of them
+}
+
+var globalCompletionFlags = []completionFlag{
+	{name: "db"},
+	{name: "debug", kind: valueBool},
+}
+
+// completionCmds mirrors the subcommands of main along with their flags and actions
+var completionCmds = []*completionCmd{
+	{names: []string{"add", "a"}, flags: []completionFlag{{name: "tags", kind: valueTags}}},
+	{names: []string{"search", "sea", "s"}, flags: []completionFlag{
+		{name: "backup-patterns"},
+		{name: "backup-variants", kind: valueBool},
+		{name: "checkpoint", kind: valueCheckpoint},
+		{name: "ext"},
+		{name: "limit"},
+		{name: "no-tag-ext", kind: valueBool},
+		{name: "o", choices: outputFormats},
+		{name: "q"},
+		{name: "rules"},
+		{name: "since"},
+		{name: "sort", choices: store.SortOrders},
+		{name: "source"},
+		{name: "st", kind: valueBool},
+		{name: "tags", kind: valueTags},
+		{name: "until"},
+	}},
+	{names: []string{"tui"}, flags: []completionFlag{
+		{name: "limit"},
+		{name: "o", choices: outputFormats},
+		{name: "out"},
+		{name: "sort", choices: store.SortOrders},
+	}},
+	{names: []string{"combine", "comb", "c"}, flags: []completionFlag{
+		{name: "dedupe", kind: valueBool},
+		{name: "left"},
+		{name: "max"},
+		{name: "right"},
+		{name: "sep"},
+		{name: "side"},
+	}},
+	{names: []string{"import", "imp", "i"}, flags: []completionFlag{
+		{name: "batch"},
+		{name: "delim"},
+		{name: "format", choices: []string{store.FormatPlain, store.FormatTagged}},
+		{name: "include"},
+		{name: "r", kind: valueBool},
+		{name: "source"},
+		{name: "tag-ignore"},
+		{name: "tag-map"},
+		{name: "tags", kind: valueTags},
+		{name: "tags-from-path", kind: valueBool},
+		{name: "undo"},
+	}},
+	{names: []string{"feedback", "fb"}, flags: []completionFlag{
+		{name: "add-unknown", kind: valueBool},
+		{name: "format", choices: feedbackFormats},
+		{name: "keyword"},
+		{name: "status"},
+		{name: "tags", kind: valueTags},
+	}},
+	{names: []string{"recommend", "rec", "r"}, flags: []completionFlag{
+		{name: "from"},
+		{name: "limit"},
+		{name: "o", choices: outputFormats},
+		{name: "per-host"},
+		{name: "sort", choices: store.SortOrders},
+	}},
+	{names: []string{"techmap", "tm"}, actions: []*completionCmd{
+		{names: []string{"ls", "list"}},
+		{names: []string{"set"}},
+		{names: []string{"rm", "delete"}},
+		{names: []string{"test"}},
+		{names: []string{"reset"}},
+	}},
+	{names: []string{"serve"}, flags: []completionFlag{
+		{name: "listen"},
+		{name: "max-upload"},
+		{name: "no-ui", kind: valueBool},
+		{name: "tokens"},
+	}},
+	{names: []string{"info"}},
+	{names: []string{"tag", "tags", "t"}, actions: []*completionCmd{
+		{names: []string{"ls", "list"}, flags: []completionFlag{{name: "n", kind: valueBool}}},
+		{names: []string{"rename", "mv"}, arg: completionFlag{kind: valueTag}, nargs: 1},
+		{names: []string{"merge"}, flags: []completionFlag{{name: "into", kind: valueTag}}, arg: completionFlag{kind: valueTag}},
+		{names: []string{"rm", "remove", "delete"}, arg: completionFlag{kind: valueTag}},
+		{names: []string{"describe", "des"}, arg: completionFlag{kind: valueTag}, nargs: 1},
+	}},
+	{names: []string{"word", "words", "w"}, actions: []*completionCmd{
+		{names: []string{"show"}},
+		{names: []string{"untag"}, flags: []completionFlag{{name: "tags", kind: valueTags}}},
+		{names: []string{"score"}, flags: []completionFlag{{name: "tags", kind: valueTags}}},
+		{names: []string{"rm", "remove", "delete"}, flags: []completionFlag{{name: "f", kind: valueBool}}},
+	}},
+	{names: []string{"batches", "batch", "b"}, actions: []*completionCmd{
+		{names: []string{"ls", "list"}},
+		{names: []string{"undo"}},
+	}},
+	{names: []string{"checkpoint", "checkpoints", "cp"}, actions: []*completionCmd{
+		{names: []string{"ls", "list"}},
+		{names: []string{"reset", "rm"}, arg: completionFlag{kind: valueCheckpoint}},
+	}},
+	{names: []string{"ext", "exts", "e"}, actions: []*completionCmd{
+		{names: []string{"ls", "list"}},
+		{names: []string{"set"}, arg: completionFlag{kind: valueExtSet}, nargs: 1},
+		{names: []string{"rm", "delete"}, arg: completionFlag{kind: valueExtSet}},
+		{names: []string{"link"}, flags: []completionFlag{{name: "tags", kind: valueTags}}, arg: completionFlag{kind: valueExtSet}, nargs: 1},
+		{names: []string{"unlink"}, flags: []completionFlag{{name: "tags", kind: valueTags}}, arg: completionFlag{kind: valueExtSet}, nargs: 1},
+	}},
+	{names: []string{"describe", "des", "d"}},
+	{names: []string{"vacuum", "vac", "v"}},
+	{names: []string{"completion"}, arg: completionFlag{choices: completionShells}, nargs: 1},
+}
+
+// The shells the completion subcommand has a script for
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// Find a subcommand or an action by any of its names
+func findCompletionCmd(cmds []*completionCmd, name string) *completionCmd {
+	for _, cmd := range cmds {
+		for _, n := range cmd.names {
+			if n == name {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// Find a flag given as -name or --name
+func findCompletionFlag(flags []completionFlag, name string) *completionFlag {
+	for i := range flags {
+		if flags[i].name == name {
+			return &flags[i]
+		}
+	}
+	return nil
+}
+
+// Walk the flags at the start of words the way the flag package parses them, returning the positional
+// arguments that follow, the flag still waiting for its value, if any, and the values seen so far.
+// args is nil when no word ends the flags, neither a positional argument nor --
+func walkFlags(words []string, flags []completionFlag) (args []string, pending *completionFlag, values map[string]string) {
+	values = make(map[string]string)
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if word == "--" {
+			return words[i+1:], nil, values
+		}
+		if len(word) < 2 || word[0] != '-' {
+			return words[i:], nil, values
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(word, "-"), "-")
+		name, value, hasValue := strings.Cut(name, "=")
+		f := findCompletionFlag(flags, name)
+		if f == nil || f.kind == valueBool || hasValue {
+			values[name] = value
+			continue
+		}
+		if i+1 == len(words) {
+			return nil, f, values
+		}
+		i++
+		values[name] = words[i]
+	}
+	return nil, nil, values
+}
+
+// completer completes the words of a command line, reading the tags and such from the database
+// of the command line only when they are needed
+type completer struct {
+	dbPath string
+	db     *store.Store
+	opened bool
+}
+
+// Open the database of the command line, the completion stays silent about any error
+// and never creates nor upgrades a database
+func (c *completer) store() *store.Store {
+	if c.opened {
+		return c.db
+	}
+	c.opened = true
+	path := c.dbPath
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	db, err := store.OpenReadOnly(path)
+	if err == nil {
+		c.db = db
+	}
+	return c.db
+}
+
+func (c *completer) Close() {
+	if c.db != nil {
+		c.db.Close()
+	}
+}
+
+// The names of the tags, extension sets or checkpoints of the database
+func (c *completer) names(kind valueKind) []string {
+	db := c.store()
+	if db == nil {
+		return nil
+	}
+	var names []string
+	switch kind {
+	case valueTags, valueTag:
+		tags, err := db.Tags()
+		if err != nil {
+			return nil
+		}
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+	case valueExtSet:
+		sets, err := db.ExtSets()
+		if err != nil {
+			return nil
+		}
+		for _, set := range sets {
+			names = append(names, set.Name)
+		}
+	case valueCheckpoint:
+		checkpoints, err := db.Checkpoints()
+		if err != nil {
+			return nil
+		}
+		for _, checkpoint := range checkpoints {
+			names = append(names, checkpoint.Name)
+		}
+	}
+	return names
+}
+
+// Complete the last word of a command line, words are the words after the program name
+// and the last one is the word under the cursor, possibly empty.
+// No candidates leaves the completion to the shell, which completes filenames
+func (c *completer) complete(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current, words := words[len(words)-1], words[:len(words)-1]
+
+	args, pending, values := walkFlags(words, globalCompletionFlags)
+	if db, ok := values["db"]; ok {
+		c.dbPath = db
+	}
+	if pending != nil {
+		return c.completeValue(pending, current, "")
+	}
+	if len(args) == 0 {
+		if strings.HasPrefix(current, "-") {
+			return c.completeFlag(globalCompletionFlags, current)
+		}
+		var names []string
+		for _, cmd := range completionCmds {
+			names = append(names, cmd.names[0])
+		}
+		return withPrefix(names, current)
+	}
+
+	cmd := findCompletionCmd(completionCmds, args[0])
+	for cmd != nil {
+		args, pending, _ = walkFlags(args[1:], cmd.flags)
+		if pending != nil {
+			return c.completeValue(pending, current, "")
+		}
+		if len(cmd.actions) == 0 || len(args) == 0 {
+			break
+		}
+		cmd = findCompletionCmd(cmd.actions, args[0])
+	}
+	if cmd == nil {
+		return nil
+	}
+
+	if args == nil && strings.HasPrefix(current, "-") {
+		return c.completeFlag(cmd.flags, current)
+	}
+	switch {
+	case len(cmd.actions) > 0:
+		var names []string
+		for _, action := range cmd.actions {
+			names = append(names, action.names[0])
+		}
+		return withPrefix(names, current)
+	case cmd.nargs > 0 && len(args) >= cmd.nargs:
+		return nil
+	}
+	return c.completeValue(&cmd.arg, current, "")
+}
+
+// Complete a flag name, or its value when given as -name=value
+func (c *completer) completeFlag(flags []completionFlag, current string) []string {
+	dashes := "-"
+	if strings.HasPrefix(current, "--") {
+		dashes = "--"
+	}
+	name, value, hasValue := strings.Cut(strings.TrimPrefix(current, dashes), "=")
+	if hasValue {
+		if f := findCompletionFlag(flags, name); f != nil {
+			return c.completeValue(f, value, dashes+name+"=")
+		}
+		return nil
+	}
+	var names []string
+	for _, f := range flags {
+		names = append(names, dashes+f.name)
+	}
+	return withPrefix(names, current)
+}
+
+// Complete the value of a flag, or a positional argument, prefix is put back in front of the candidates
+func (c *completer) completeValue(f *completionFlag, current, prefix string) []string {
+	var candidates []string
+	switch {
+	case len(f.choices) > 0:
+		candidates = withPrefix(f.choices, current)
+	case f.kind == valueTags:
+		// only the last tag of the list is completed, leaving out the ones already listed
+		head, last := "", current
+		if i := strings.LastIndex(current, ","); i >= 0 {
+			head, last = current[:i+1], current[i+1:]
+		}
+		listed := make(map[string]bool)
+		for _, tag := range strings.Split(head, ",") {
+			listed[strings.TrimSpace(tag)] = true
+		}
+		for _, tag := range withPrefix(c.names(f.kind), last) {
+			if !listed[tag] {
+				candidates = append(candidates, head+tag)
+			}
+		}
+	case f.kind == valueTag || f.kind == valueExtSet || f.kind == valueCheckpoint:
+		candidates = withPrefix(c.names(f.kind), current)
+	}
+	if prefix != "" {
+		for i := range candidates {
+			candidates[i] = prefix + candidates[i]
+		}
+	}
+	return candidates
+}
+
+// The names starting with prefix
+func withPrefix(names []string, prefix string) []string {
+	var matches []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The completion scripts call orunmila __complete with the words of the command line after the
+// program name, the word under the cursor last, and complete filenames when it prints nothing
+var completionScripts = map[string]string{
+	"bash": `# bash completion for orunmila, load it with
+#   source <(orunmila completion bash)
+_orunmila() {
+	local line="${COMP_LINE:0:COMP_POINT}" cur prefix candidate
+	local -a words
+	read -r -a words <<< "$line"
+	if [[ -z $line || $line == *[[:space:]] ]]; then
+		words+=("")
+	fi
+	cur="${words[${#words[@]}-1]}"
+	# bash only replaces what follows the last = or : of the word, see COMP_WORDBREAKS
+	prefix="${cur%"${cur##*[=:]}"}"
+	COMPREPLY=()
+	while IFS= read -r candidate; do
+		COMPREPLY+=("${candidate#"$prefix"}")
+	done < <(orunmila __complete "${words[@]:1}" 2>/dev/null)
+}
+complete -o default -F _orunmila orunmila
+`,
+	"zsh": `#compdef orunmila
+# zsh completion for orunmila, load it with
+#   source <(orunmila completion zsh)
+# or save it as _orunmila in a directory of $fpath
+_orunmila() {
+	local -a candidates
+	candidates=("${(@f)$(orunmila __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	candidates=(${candidates:#})
+	if (( ${#candidates} )); then
+		compadd -- "${candidates[@]}"
+	else
+		_files
+	fi
+}
+
+if [[ $funcstack[1] == _orunmila ]]; then
+	_orunmila "$@"
+else
+	compdef _orunmila orunmila
+fi
+`,
+	"fish": `# fish completion for orunmila, load it with
+#   orunmila completion fish | source
+function __orunmila_complete
+	set -l words (commandline -opc)
+	set -e words[1]
+	set -l cur (commandline -ct)
+	set -l candidates (orunmila __complete $words "$cur" 2>/dev/null)
+	if test (count $candidates) -eq 0
+		__fish_complete_path "$cur"
+		return
+	end
+	printf '%s\n' $candidates
+end
+complete -c orunmila -f -a '(__orunmila_complete)'
+`,
+}
+
+// parse args of the completion subcommand and print the completion script of a shell
+func completionSubcmd(args []string) error {
+	completionCmd := flag.NewFlagSet("completion", flag.ContinueOnError)
+
+	completionCmd.SetOutput(flag.CommandLine.Output())
+
+	completionCmd.Usage = func() {
+		fmt.Fprint(completionCmd.Output(), "Print the shell completion script of orunmila\n\n")
+		fmt.Fprintln(completionCmd.Output(), "Usage of orunmila completion:")
+		fmt.Fprintf(completionCmd.Output(), "orunmila completion %s\n\n", strings.Join(completionShells, "|"))
+		fmt.Fprintln(completionCmd.Output(), "The subcommands, their flags and actions are completed, along with the tags of -tags,")
+		fmt.Fprintln(completionCmd.Output(), "which are read from the database given with -db on the command line being completed")
+		fmt.Fprintln(completionCmd.Output(), "\texample: source <(orunmila completion bash)")
+		fmt.Fprintln(completionCmd.Output(), "\texample: orunmila completion zsh > \"${fpath[1]}/_orunmila\"")
+		fmt.Fprintln(completionCmd.Output(), "\texample: orunmila completion fish > ~/.config/fish/completions/orunmila.fish")
+	}
+
+	err := completionCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	if completionCmd.NArg() != 1 {
+		completionCmd.Usage()
+		err = fmt.Errorf("completion needs one shell, %s", strings.Join(completionShells, ", "))
+		log.Errorln("[completionSubcmd]", err)
+		return err
+	}
+
+	script, ok := completionScripts[completionCmd.Arg(0)]
+	if !ok {
+		err = fmt.Errorf("no completion for shell %q, expected %s", completionCmd.Arg(0), strings.Join(completionShells, ", "))
+		log.Errorln("[completionSubcmd]", err)
+		return err
+	}
+	_, err = fmt.Print(script)
+	return err
+}
+
+// print the candidates of the last word of a command line, one per line, for the completion scripts
+func completeSubcmd(args []string) {
+	c := &completer{dbPath: *dbPtr}
+	defer c.Close()
+
+	for _, candidate := range c.complete(args) {
+		fmt.Println(candidate)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompletionSubcmd(t *testing.T) {
+	buf.Reset()
+	assert.Error(t, completionSubcmd([]string{}))
+	assert.Contains(t, buf.String(), "completion needs one shell, bash, zsh, fish")
+
+	buf.Reset()
+	assert.Error(t, completionSubcmd([]string{"ksh"}))
+	assert.Contains(t, buf.String(), `no completion for shell \"ksh\"`)
+
+	assert.Len(t, completionScripts, len(completionShells))
+	for _, shell := range completionShells {
+		script, ok := completionScripts[shell]
+		if assert.True(t, ok, shell) {
+			assert.Contains(t, script, "orunmila "+completeCmdName+" ", shell)
+		}
+	}
+}
+
+func TestCompletionScriptSyntax(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		path, err := exec.LookPath(shell)
+		if err != nil {
+			t.Log(shell, "not found, skipping the syntax check of its script")
+			continue
+		}
+		cmd := exec.Command(path, "-n")
+		cmd.Stdin = strings.NewReader(completionScripts[shell])
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, "%s: %s", shell, out)
+	}
+}
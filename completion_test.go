@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplete(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "php,web,wordpress", "index.php"})
+	assert.NoError(t, extSubcmd([]string{"set", "php", "php,inc"}))
+
+	c := &completer{dbPath: *dbPtr}
+	defer c.Close()
+
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{""}, []string{"add", "search", "tui", "combine", "import", "feedback", "recommend", "techmap", "serve", "info", "tag", "word", "batches", "checkpoint", "ext", "describe", "vacuum", "completion"}},
+		{[]string{"se"}, []string{"search", "serve"}},
+		{[]string{"-d"}, []string{"-db", "-debug"}},
+		{[]string{"-db", ""}, nil},
+		{[]string{"search", "-s"}, []string{"-since", "-sort", "-source", "-st"}},
+		{[]string{"s", "--ta"}, []string{"--tags"}},
+		{[]string{"search", "-tags", ""}, []string{"php", "web", "wordpress"}},
+		{[]string{"search", "-tags", "w"}, []string{"web", "wordpress"}},
+		{[]string{"search", "-tags", "web,"}, []string{"web,php", "web,wordpress"}},
+		{[]string{"search", "-tags", "php,wordpress,w"}, []string{"php,wordpress,web"}},
+		{[]string{"search", "-tags=php,w"}, []string{"-tags=php,web", "-tags=php,wordpress"}},
+		{[]string{"search", "-st", "-tags", "p"}, []string{"php"}},
+		{[]string{"search", "-o", "t"}, []string{"tsv", "tagged"}},
+		{[]string{"search", "--sort="}, []string{"--sort=score", "--sort=alpha", "--sort=length", "--sort=added"}},
+		{[]string{"search", "-rules", ""}, nil},
+		{[]string{"search", "word", "-"}, nil},
+		{[]string{"search", "--", "-t"}, nil},
+		{[]string{"import", "-format", ""}, []string{"plain", "tagged"}},
+		{[]string{"feedback", "-format", "f"}, []string{"ffuf-json", "feroxbuster-json"}},
+		{[]string{"tag", ""}, []string{"ls", "rename", "merge", "rm", "describe"}},
+		{[]string{"t", "rm", "php", "w"}, []string{"web", "wordpress"}},
+		{[]string{"tag", "merge", "-into", "p"}, []string{"php"}},
+		{[]string{"tag", "rename", "php", ""}, nil},
+		{[]string{"tag", "ls", "-"}, []string{"-n"}},
+		{[]string{"word", "untag", "-tags", "php,"}, []string{"php,web", "php,wordpress"}},
+		{[]string{"ext", "link", "-tags", "php", ""}, []string{"php"}},
+		{[]string{"ext", "unknown", ""}, nil},
+		{[]string{"completion", ""}, []string{"bash", "zsh", "fish"}},
+		{[]string{"nope", ""}, nil},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, c.complete(test.words), "%q", test.words)
+	}
+}
+
+func TestCompleteDb(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "php", "index.php"})
+
+	// the db of the command line is used, a missing one is not created
+	c := &completer{dbPath: "missing.db"}
+	defer c.Close()
+	assert.Nil(t, c.complete([]string{"search", "-tags", ""}))
+	assert.NoFileExists(t, "missing.db")
+
+	c = &completer{dbPath: "missing.db"}
+	defer c.Close()
+	assert.Equal(t, []string{"php"}, c.complete([]string{"-db", *dbPtr, "search", "-tags", ""}))
+
+	c = &completer{dbPath: "missing.db"}
+	defer c.Close()
+	assert.Equal(t, []string{"php"}, c.complete([]string{"--db=" + *dbPtr, "-debug", "a", "-tags", "p"}))
+}
+
+// The flags listed by the usage of a subcommand
+func usageFlags(t *testing.T, run func() error) []string {
+	buf.Reset()
+	assert.Error(t, run())
+	var flags []string
+	scanner := bufio.NewScanner(strings.NewReader(buf.String()))
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "  -") {
+			flags = append(flags, strings.Fields(line)[0][1:])
+		}
+	}
+	sort.Strings(flags)
+	return flags
+}
+
+// The completion knows about every flag and action of the subcommands
+func TestCompletionCmds(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+
+	subcmds := map[string]func([]string) error{
+		"search":     searchSubcmd,
+		"tui":        tuiSubcmd,
+		"combine":    combineSubcmd,
+		"feedback":   feedbackSubcmd,
+		"recommend":  recommendSubcmd,
+		"serve":      serveSubcmd,
+		"techmap":    techmapSubcmd,
+		"tag":        tagSubcmd,
+		"word":       wordSubcmd,
+		"batches":    batchesSubcmd,
+		"checkpoint": checkpointSubcmd,
+		"ext":        extSubcmd,
+	}
+	for name, subcmd := range subcmds {
+		cmd := findCompletionCmd(completionCmds, name)
+		if !assert.NotNil(t, cmd, name) {
+			continue
+		}
+		if len(cmd.actions) == 0 {
+			var names []string
+			for _, f := range cmd.flags {
+				names = append(names, f.name)
+			}
+			assert.Equal(t, names, usageFlags(t, func() error { return subcmd([]string{"-help"}) }), name)
+			continue
+		}
+		for _, action := range cmd.actions {
+			var names []string
+			for _, f := range action.flags {
+				names = append(names, f.name)
+			}
+			flags := usageFlags(t, func() error { return subcmd([]string{action.names[0], "-help"}) })
+			assert.Equal(t, names, flags, "%s %s", name, action.names[0])
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"database/sql"
 	"flag"
 	"fmt"
 	"os"
@@ -33,22 +32,9 @@ func describeSubcmd(args []string) {
 		os.Exit(1)
 	}
 
-	dsn := fmt.Sprintf("file:%s?mode=rw", *dbPtr)
-	db, err := sql.Open("sqlite3", dsn)
-	check(err)
+	db := openStore()
 	defer db.Close()
 
-	tx, err := db.Begin()
-	check(err)
-
-	descStmt, err := tx.Prepare("INSERT OR REPLACE INTO sysconfig(name,val) values (?,?)")
-	check(err)
-	defer descStmt.Close()
-
-	desc := strings.TrimSpace(strings.Join(args, " "))
-	_, err = descStmt.Exec("description", desc)
-	check(err)
-
-	err = tx.Commit()
+	err := db.Describe(strings.Join(args, " "))
 	check(err)
 }
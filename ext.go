@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/proditis/orunmila/store"
+)
+
+// The placeholder of dirsearch style wordlists, replaced by every extension
+const extPlaceholder = "%EXT%"
+
+// Check if a word is a bare file name, one without an extension nor a trailing slash
+func isBareWord(word string) bool {
+	if word == "" || strings.HasSuffix(word, "/") {
+		return false
+	}
+	return !strings.Contains(word[strings.LastIndex(word, "/")+1:], ".")
+}
+
+// Expand a word with extensions. The %EXT% placeholders are replaced by every extension,
+// bare words are kept and followed by one candidate per extension, other words are kept as they are.
+func expandExtensions(word string, exts []string) []string {
+	if strings.Contains(word, extPlaceholder) {
+		candidates := make([]string, 0, len(exts))
+		for _, ext := range exts {
+			candidates = append(candidates, strings.ReplaceAll(word, extPlaceholder, ext))
+		}
+		return candidates
+	}
+	if !isBareWord(word) {
+		return []string{word}
+	}
+	candidates := make([]string, 0, len(exts)+1)
+	candidates = append(candidates, word)
+	for _, ext := range exts {
+		candidates = append(candidates, word+"."+ext)
+	}
+	return candidates
+}
+
+// Resolve the extensions given to search -ext, where @name stands for the extensions of a set,
+// along with the extensions of the sets linked to the tags of the query when linked is set
+func resolveExtensions(db *store.Store, list []string, query store.Expr, linked bool) ([]string, error) {
+	var exts []string
+	for _, item := range list {
+		if !strings.HasPrefix(item, "@") {
+			exts = append(exts, item)
+			continue
+		}
+		set, err := db.ExtSetExtensions(item[1:])
+		if err != nil {
+			return nil, err
+		}
+		exts = append(exts, set...)
+	}
+	if linked && query != nil {
+		set, err := db.TagExtensions(store.IncludedTagNames(query))
+		if err != nil {
+			return nil, err
+		}
+		exts = append(exts, set...)
+	}
+	return store.NormalizeExtensions(exts), nil
+}
+
+// extExpansion expands a word with the extensions
+func extExpansion(exts []string) func(string) []string {
+	return func(word string) []string {
+		return expandExtensions(word, exts)
+	}
+}
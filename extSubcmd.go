@@ -0,0 +1,143 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// parse args of the ext subcommand and exec the requested action
+func extSubcmd(args []string) error {
+	extCmd := flag.NewFlagSet("ext", flag.ContinueOnError)
+
+	extCmd.SetOutput(flag.CommandLine.Output())
+
+	extCmd.Usage = func() {
+		fmt.Fprint(extCmd.Output(), "Manage the extension sets of search -ext and the tags they are linked to\n\n")
+		fmt.Fprintln(extCmd.Output(), "Usage of orunmila ext:")
+		fmt.Fprintln(extCmd.Output(), "orunmila [-db <db_path>] [-debug] ext <action> [arguments]")
+		fmt.Fprintln(extCmd.Output(), "\nActions")
+		fmt.Fprintln(extCmd.Output(), "  ls                              List the extension sets along with their tags")
+		fmt.Fprintln(extCmd.Output(), "  set <name> <extensions>         Create an extension set or replace its extensions")
+		fmt.Fprintln(extCmd.Output(), "  rm <names>...                   Delete extension sets")
+		fmt.Fprintln(extCmd.Output(), "  link -tags <tags> <name>        Add the extensions of a set to the searches of tags")
+		fmt.Fprintln(extCmd.Output(), "  unlink -tags <tags> <name>      Remove the links of a set to tags")
+	}
+
+	return runAction(extCmd, args, []subcmdAction{
+		{names: []string{"ls", "list"}, run: extLs},
+		{names: []string{"set"}, run: extSet},
+		{names: []string{"rm", "delete"}, run: extRm},
+		{names: []string{"link"}, run: func(args []string) error { return extLink(args, true) }},
+		{names: []string{"unlink"}, run: func(args []string) error { return extLink(args, false) }},
+	})
+}
+
+// list the extension sets along with their tags
+func extLs(args []string) error {
+	cmd := newActionFlagSet("ext", "ls", "ls", "List the extension sets along with their tags")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+
+	db := openStoreReadOnly()
+	defer db.Close()
+
+	sets, err := db.ExtSets()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SET\tEXTENSIONS\tTAGS")
+	for _, set := range sets {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", set.Name, strings.Join(set.Extensions, ","), strings.Join(set.Tags, ","))
+	}
+	return w.Flush()
+}
+
+// create an extension set or replace its extensions
+func extSet(args []string) error {
+	cmd := newActionFlagSet("ext", "set", "set <name> <extensions>", "Create an extension set or replace its extensions, given as a comma separated list")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	if cmd.NArg() != 2 {
+		cmd.Usage()
+		return fmt.Errorf("set needs a name and a list of extensions")
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	name, exts := cmd.Arg(0), splitList(cmd.Arg(1))
+	if err := db.SetExtSet(name, exts); err != nil {
+		return err
+	}
+	log.Infof("[extSet] extension set %q: %s", name, strings.Join(exts, ","))
+	return nil
+}
+
+// delete extension sets
+func extRm(args []string) error {
+	cmd := newActionFlagSet("ext", "rm", "rm <names>...", "Delete extension sets along with their tag links")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	if cmd.NArg() == 0 {
+		cmd.Usage()
+		return fmt.Errorf("rm needs at least one extension set")
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	for _, name := range cmd.Args() {
+		if err := db.DeleteExtSet(name); err != nil {
+			return err
+		}
+		log.Infof("[extRm] deleted extension set %q", name)
+	}
+	return nil
+}
+
+// link an extension set to tags, or unlink it
+func extLink(args []string, link bool) error {
+	action, description := "link", "Add the extensions of a set to the searches of tags, missing tags are created"
+	if !link {
+		action, description = "unlink", "Remove the links of an extension set to tags"
+	}
+	cmd := newActionFlagSet("ext", action, action+" -tags <tags> <name>", description)
+	tagsPtr := cmd.String("tags", "", "a comma separated list of tags")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	tags := splitList(*tagsPtr)
+	if cmd.NArg() != 1 || len(tags) == 0 {
+		cmd.Usage()
+		return fmt.Errorf("%s needs -tags and an extension set", action)
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	name := cmd.Arg(0)
+	if link {
+		n, err := db.LinkExtSet(name, tags)
+		if err != nil {
+			return err
+		}
+		log.Infof("[extLink] linked extension set %q to %d tags", name, n)
+		return nil
+	}
+	n, err := db.UnlinkExtSet(name, tags)
+	if err != nil {
+		return err
+	}
+	log.Infof("[extLink] unlinked extension set %q from %d tags", name, n)
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/proditis/orunmila/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtSubcmdActions(t *testing.T) {
+	assert.Error(t, extSubcmd([]string{"link", "php"}))
+
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+
+	assert.NoError(t, extSubcmd([]string{"set", "php", "php,phtml"}))
+	assert.NoError(t, extSubcmd([]string{"set", "backup", ".bak,.old"}))
+	assert.NoError(t, extSubcmd([]string{"link", "-tags", "php", "php"}))
+	assert.Error(t, extSubcmd([]string{"link", "-tags", "php", "missing"}))
+	assert.NoError(t, extSubcmd([]string{"ls"}))
+
+	db := openStore()
+	sets, err := db.ExtSets()
+	db.Close()
+	assert.NoError(t, err)
+	if assert.Len(t, sets, 2) {
+		assert.Equal(t, []string{"bak", "old"}, sets[0].Extensions)
+		assert.Equal(t, []string{"php"}, sets[1].Tags)
+	}
+
+	assert.NoError(t, extSubcmd([]string{"unlink", "-tags", "php", "php"}))
+	assert.NoError(t, extSubcmd([]string{"rm", "php", "backup"}))
+	assert.Error(t, extSubcmd([]string{"rm", "php"}))
+}
+
+// Search the words of tags expanded with the extensions of search -ext
+func searchWithExtensions(db *store.Store, tags, ext string) (string, error) {
+	query := store.TagsQuery(splitList(tags))
+	exts, err := resolveExtensions(db, splitList(ext), query, true)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	next, _ := newResultWriter("plain", &out, false)
+	err = searchWords(db, store.SearchOptions{Query: query}, newExpandWriter(next, extExpansion(exts)))
+	return out.String(), err
+}
+
+func TestSearchSubcmdExt(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "php", "admin", "index.%EXT%"})
+	addSubcmd([]string{"-tags", "other", "login"})
+
+	db := openStore()
+	_, err := searchWithExtensions(db, "php", "bak,@php")
+	assert.Error(t, err)
+	assert.NoError(t, db.SetExtSet("php", []string{"php", "phtml"}))
+	_, err = db.LinkExtSet("php", []string{"php"})
+	assert.NoError(t, err)
+
+	words, err := searchWithExtensions(db, "php", "bak")
+	assert.NoError(t, err)
+	assert.Equal(t, "admin\nadmin.bak\nadmin.php\nadmin.phtml\nindex.bak\nindex.php\nindex.phtml\n", words)
+	words, err = searchWithExtensions(db, "other", "@php")
+	assert.NoError(t, err)
+	assert.Equal(t, "login\nlogin.php\nlogin.phtml\n", words)
+	db.Close()
+
+	assert.NoError(t, searchSubcmd([]string{"-tags", "php", "-ext", "bak", "-no-tag-ext"}))
+	assert.Contains(t, buf.String(), "using extensions: bak")
+	assert.Error(t, searchSubcmd([]string{"-tags", "php", "-ext", "@missing"}))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/proditis/orunmila/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsBareWord(t *testing.T) {
+	for word, bare := range map[string]bool{
+		"admin":           true,
+		"api/v1/users":    true,
+		"index.php":       false,
+		".htaccess":       false,
+		"admin/":          false,
+		"v1.2/users":      true,
+		"backup.tar.gz":   false,
+		"":                false,
+		"assets/app.js":   false,
+		"assets.old/site": true,
+	} {
+		assert.Equalf(t, bare, isBareWord(word), "%q", word)
+	}
+}
+
+func TestExpandExtensions(t *testing.T) {
+	exts := []string{"php", "bak"}
+	assert.Equal(t, []string{"admin", "admin.php", "admin.bak"}, expandExtensions("admin", exts))
+	assert.Equal(t, []string{"index.php", "index.bak"}, expandExtensions("index.%EXT%", exts))
+	assert.Equal(t, []string{"php/x.php", "bak/x.bak"}, expandExtensions("%EXT%/x.%EXT%", exts))
+	assert.Equal(t, []string{"config.inc"}, expandExtensions("config.inc", exts))
+	assert.Equal(t, []string{"admin/"}, expandExtensions("admin/", exts))
+}
+
+func TestExtExpansion(t *testing.T) {
+	var out bytes.Buffer
+	next, _ := newResultWriter("plain", &out, false)
+	w := newExpandWriter(next, extExpansion([]string{"php", "bak"}))
+	assert.NoError(t, w.Write(store.Record{Word: "admin", ID: 1}))
+	assert.NoError(t, w.Write(store.Record{Word: "admin.php", ID: 2}))
+	assert.NoError(t, w.Write(store.Record{Word: "login.%EXT%", ID: 3}))
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "admin\nadmin.php\nadmin.bak\nlogin.php\nlogin.bak\n", out.String())
+	assert.Equal(t, int64(5), w.candidates)
+	assert.Equal(t, int64(1), w.duplicates)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The result formats read by the feedback subcommand
+var feedbackFormats = []string{"ffuf-json", "feroxbuster-json", "gobuster"}
+
+// feedbackHit is a single result of a fuzzer
+type feedbackHit struct {
+	// input is the wordlist entry that produced the hit, when the format records it
+	input string
+	// path is the path of the discovered URL, without its leading slash
+	path   string
+	status int
+}
+
+// Parse the results of a fuzzer in the given format. keyword is the ffuf keyword of the wordlist.
+func parseFeedback(format string, r io.Reader, keyword string) ([]feedbackHit, error) {
+	switch format {
+	case "ffuf-json", "ffuf":
+		return parseFfufJSON(r, keyword)
+	case "feroxbuster-json", "feroxbuster":
+		return parseFeroxbusterJSON(r)
+	case "gobuster":
+		return parseGobuster(r)
+	}
+	return nil, fmt.Errorf("unknown feedback format %q, valid formats are %s", format, strings.Join(feedbackFormats, ", "))
+}
+
+// Extract the path of a URL, without its leading slash and query
+func urlPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return strings.TrimPrefix(rawURL, "/")
+	}
+	return strings.TrimPrefix(u.Path, "/")
+}
+
+// Parse the output of ffuf -of json
+func parseFfufJSON(r io.Reader, keyword string) ([]feedbackHit, error) {
+	var output struct {
+		Results []struct {
+			Input  map[string]string `json:"input"`
+			URL    string            `json:"url"`
+			Status int               `json:"status"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(r).Decode(&output); err != nil {
+		return nil, fmt.Errorf("invalid ffuf json: %v", err)
+	}
+	hits := make([]feedbackHit, 0, len(output.Results))
+	for _, result := range output.Results {
+		hits = append(hits, feedbackHit{input: result.Input[keyword], path: urlPath(result.URL), status: result.Status})
+	}
+	return hits, nil
+}
+
+// Parse the output of feroxbuster --json, one JSON object per line, keeping the responses
+func parseFeroxbusterJSON(r io.Reader) ([]feedbackHit, error) {
+	var hits []feedbackHit
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var entry struct {
+			Type   string `json:"type"`
+			URL    string `json:"url"`
+			Status int    `json:"status"`
+		}
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			return nil, fmt.Errorf("invalid feroxbuster json at line %d: %v", line, err)
+		}
+		if entry.Type == "response" {
+			hits = append(hits, feedbackHit{path: urlPath(entry.URL), status: entry.Status})
+		}
+	}
+	return hits, scanner.Err()
+}
+
+// A result line of gobuster dir, ie /admin (Status: 301) [Size: 178] [--> http://target/admin/]
+var gobusterLine = regexp.MustCompile(`^(\S+)\s+\(Status:\s*(\d+)\)`)
+
+// Parse the output of gobuster dir, skipping the banner and progress lines
+func parseGobuster(r io.Reader) ([]feedbackHit, error) {
+	var hits []feedbackHit
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// progress updates are overwritten with carriage returns
+		text := scanner.Text()
+		if i := strings.LastIndex(text, "\r"); i >= 0 {
+			text = text[i+1:]
+		}
+		m := gobusterLine.FindStringSubmatch(strings.TrimSpace(text))
+		if m == nil {
+			continue
+		}
+		status, _ := strconv.Atoi(m[2])
+		hits = append(hits, feedbackHit{path: urlPath(m[1]), status: status})
+	}
+	return hits, scanner.Err()
+}
+
+// The words a hit may come from, most specific first: the wordlist entry when known,
+// otherwise the whole path, its last segment, then both of them without their extension
+func (h feedbackHit) candidates() []string {
+	if h.input != "" {
+		return []string{h.input}
+	}
+	var candidates []string
+	seen := make(map[string]bool)
+	add := func(word string) {
+		if word != "" && !seen[word] {
+			seen[word] = true
+			candidates = append(candidates, word)
+		}
+	}
+	p := h.path
+	add(p)
+	add(strings.TrimSuffix(p, "/"))
+	base := path.Base(strings.TrimSuffix(p, "/"))
+	if base != "." && base != "/" {
+		add(base)
+	}
+	if ext := path.Ext(p); ext != "" && ext != p {
+		add(strings.TrimSuffix(p, ext))
+	}
+	if ext := path.Ext(base); ext != "" && ext != base {
+		add(strings.TrimSuffix(base, ext))
+	}
+	return candidates
+}
+
+// The word recorded for a hit that matches no word of the database: the wordlist entry when known,
+// the last segment of the path otherwise
+func (h feedbackHit) unknownWord() string {
+	if h.input != "" {
+		return h.input
+	}
+	base := path.Base(strings.TrimSuffix(h.path, "/"))
+	if base == "." || base == "/" {
+		return ""
+	}
+	return base
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/proditis/orunmila/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// The tag given to the words added by feedback -add-unknown
+const discoveredTag = "discovered"
+
+// parse args of the feedback subcommand and exec it
+func feedbackSubcmd(args []string) error {
+	feedbackCmd := flag.NewFlagSet("feedback", flag.ContinueOnError)
+
+	feedbackCmd.SetOutput(flag.CommandLine.Output())
+
+	feedbackCmd.Usage = func() {
+		fmt.Fprint(feedbackCmd.Output(), "Credit the words found by a fuzzer with hits, which count towards their score\n\n")
+		fmt.Fprintln(feedbackCmd.Output(), "Usage of orunmila feedback:")
+		fmt.Fprintf(feedbackCmd.Output(), "orunmila [-db <db_path>] [-debug] feedback [-format FORMAT] [-tags TAGS] [-status CODES] [-add-unknown] [filenames]...\n\n")
+		fmt.Fprintln(feedbackCmd.Output(), "  filename\n\tthe result files of the fuzzer, - reads from the standard input")
+		feedbackCmd.PrintDefaults()
+		fmt.Fprintln(feedbackCmd.Output(), "\nThe discovered paths are mapped back to the words of the database, the whole path first, then its last segment, then both without their extension")
+		fmt.Fprintln(feedbackCmd.Output(), "\texample: orunmila feedback -format ffuf-json -tags drupal results.json")
+		fmt.Fprintln(feedbackCmd.Output(), "\texample: feroxbuster -u https://target --json -o - | orunmila feedback -format feroxbuster-json -add-unknown -")
+	}
+
+	var (
+		formatPtr     = feedbackCmd.String("format", "ffuf-json", "the format of the results ("+strings.Join(feedbackFormats, "|")+")")
+		tagsPtr       = feedbackCmd.String("tags", "", "a comma separated list of tags, the hits are also recorded on the associations of the words with them")
+		keywordPtr    = feedbackCmd.String("keyword", "FUZZ", "the ffuf keyword of the wordlist")
+		statusPtr     = feedbackCmd.String("status", "", "a comma separated list of the status codes that count as hits (default: all the results)")
+		addUnknownPtr = feedbackCmd.Bool("add-unknown", false, "add the discovered words missing from the database, tagged with "+discoveredTag+" and -tags")
+	)
+
+	err := feedbackCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	log.Debugln("[feedbackSubcmd] using db:", *dbPtr)
+	log.Debugln("[feedbackSubcmd] format:", *formatPtr, "tags:", *tagsPtr, "keyword:", *keywordPtr)
+	log.Debugln("[feedbackSubcmd] status:", *statusPtr, "add unknown:", *addUnknownPtr)
+
+	if feedbackCmd.NArg() == 0 {
+		feedbackCmd.Usage()
+		err = fmt.Errorf("feedback needs at least one result file")
+		log.Errorln("[feedbackSubcmd]", err)
+		return err
+	}
+	statuses := make(map[int]bool)
+	for _, code := range splitList(*statusPtr) {
+		n, err := strconv.Atoi(code)
+		if err != nil {
+			err = fmt.Errorf("invalid status code %q", code)
+			log.Errorln("[feedbackSubcmd]", err)
+			return err
+		}
+		statuses[n] = true
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	batchID, err := db.NewBatch("feedback " + strings.Join(args, " "))
+	if err != nil {
+		log.Errorln("[feedbackSubcmd]", err)
+		return err
+	}
+	f := feedback{db: db, tags: splitList(*tagsPtr), statuses: statuses, addUnknown: *addUnknownPtr, batchID: batchID, known: make(map[string]bool)}
+	for _, filename := range feedbackCmd.Args() {
+		if err = f.importFile(filename, *formatPtr, *keywordPtr); err != nil {
+			log.Errorln("[feedbackSubcmd]", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// feedback credits the words of result files with their hits
+type feedback struct {
+	db   *store.Store
+	tags []string
+	// the status codes that count as hits, all of them when empty
+	statuses   map[int]bool
+	addUnknown bool
+	batchID    int64
+	// whether the words looked up so far exist
+	known map[string]bool
+}
+
+// Check if a word exists, caching the answer
+func (f *feedback) exists(word string) (bool, error) {
+	if known, ok := f.known[word]; ok {
+		return known, nil
+	}
+	id, err := f.db.WordID(word)
+	if err != nil {
+		return false, err
+	}
+	f.known[word] = id > 0
+	return id > 0, nil
+}
+
+// Map the hits of a file to words and record them
+func (f *feedback) importFile(filename, format, keyword string) error {
+	file, err := openInput(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	results, err := parseFeedback(format, file, keyword)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	hits, unknown := make(map[string]int64), make(map[string]int64)
+	var total int64
+	for _, hit := range results {
+		if len(f.statuses) > 0 && !f.statuses[hit.status] {
+			continue
+		}
+		total++
+		var word string
+		for _, candidate := range hit.candidates() {
+			known, err := f.exists(candidate)
+			if err != nil {
+				return err
+			}
+			if known {
+				word = candidate
+				break
+			}
+		}
+		if word != "" {
+			hits[word]++
+		} else if word = hit.unknownWord(); word != "" {
+			unknown[word]++
+		}
+	}
+
+	info, err := f.db.RecordHits(hits, f.tags, f.batchID)
+	if err != nil {
+		return err
+	}
+	log.Infof("[feedback] %s: %d hits, %d words credited, %d associations added, %d unknown words", filename, total, info.Words, info.Links, len(unknown))
+	if len(unknown) == 0 {
+		return nil
+	}
+	if !f.addUnknown {
+		log.Infof("[feedback] %s: use -add-unknown to add the %d unknown words", filename, len(unknown))
+		return nil
+	}
+
+	names := make([]string, 0, len(unknown))
+	for word := range unknown {
+		names = append(names, word)
+		f.known[word] = true
+	}
+	opts := store.ImportOptions{Tags: mergeLists([]string{discoveredTag}, f.tags), Source: filename, BatchID: f.batchID}
+	if filename == "-" {
+		opts.Source = "<stdin>"
+	}
+	if _, err = f.db.ImportReader(strings.NewReader(strings.Join(names, "\n")), opts); err != nil {
+		return err
+	}
+	if info, err = f.db.RecordHits(unknown, f.tags, f.batchID); err != nil {
+		return err
+	}
+	log.Infof("[feedback] %s: added %d unknown words", filename, info.Words)
+	return nil
+}
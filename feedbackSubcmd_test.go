@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeedbackSubcmd(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "php", "admin", "login", "config"})
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		filename := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(filename, []byte(content), 0644))
+		return filename
+	}
+	ferox := write("ferox.json", feroxbusterResults)
+	gobuster := write("gobuster.txt", gobusterResults)
+
+	assert.Equal(t, flag.ErrHelp, feedbackSubcmd([]string{"-help"}))
+	assert.EqualError(t, feedbackSubcmd([]string{}), "feedback needs at least one result file")
+	assert.EqualError(t, feedbackSubcmd([]string{"-status", "ok", gobuster}), `invalid status code "ok"`)
+	assert.Error(t, feedbackSubcmd([]string{"-format", "ffuf-json", gobuster}))
+
+	// admin/ and config.php are credited, login is filtered out by status
+	assert.NoError(t, feedbackSubcmd([]string{"-format", "feroxbuster-json", "-tags", "php", ferox}))
+	assert.NoError(t, feedbackSubcmd([]string{"-format", "gobuster", "-status", "200,301", "-add-unknown", gobuster}))
+
+	db := openStoreReadOnly()
+	defer db.Close()
+	for word, hits := range map[string]int64{"admin": 2, "config": 1, "login": 0, "backup.zip": 1} {
+		info, err := db.Word(word)
+		if assert.NoErrorf(t, err, word) {
+			assert.Equalf(t, hits, info.Hits, word)
+		}
+	}
+	info, err := db.Word("backup.zip")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{discoveredTag}, info.Tags)
+}
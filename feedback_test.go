@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const ffufResults = `{"commandline":"ffuf -w words.txt -u https://target/FUZZ","time":"2026-10-18T10:00:00Z","results":[
+{"input":{"FFUFHASH":"1a2b","FUZZ":"admin"},"position":1,"status":301,"length":178,"url":"https://target/admin","redirectlocation":"https://target/admin/"},
+{"input":{"FUZZ":"user/login"},"position":2,"status":200,"length":1024,"url":"https://target/user/login?x=1"}
+],"config":{}}`
+
+const feroxbusterResults = `{"type":"configuration","target_url":"https://target"}
+{"type":"response","url":"https://target/admin/","original_url":"https://target","path":"/admin/","status":301,"method":"GET"}
+{"type":"response","url":"https://target/admin/config.php","path":"/admin/config.php","status":200,"method":"GET"}
+
+{"type":"statistics","requests":100}
+`
+
+const gobusterResults = "===============================================================\n" +
+	"Gobuster v3.6\n" +
+	"[+] Url:                     https://target\n" +
+	"===============================================================\n" +
+	"Starting gobuster in directory enumeration mode\n" +
+	"/admin                (Status: 301) [Size: 178] [--> https://target/admin/]\n" +
+	"\rProgress: 10 / 100 (10.00%)\r/backup.zip           (Status: 200) [Size: 2048]\n" +
+	"https://target/login (Status: 403)\n" +
+	"Progress: 100 / 100 (100.00%)\n"
+
+func TestParseFeedback(t *testing.T) {
+	hits, err := parseFeedback("ffuf-json", strings.NewReader(ffufResults), "FUZZ")
+	assert.NoError(t, err)
+	assert.Equal(t, []feedbackHit{{input: "admin", path: "admin", status: 301}, {input: "user/login", path: "user/login", status: 200}}, hits)
+
+	hits, err = parseFeedback("feroxbuster-json", strings.NewReader(feroxbusterResults), "FUZZ")
+	assert.NoError(t, err)
+	assert.Equal(t, []feedbackHit{{path: "admin/", status: 301}, {path: "admin/config.php", status: 200}}, hits)
+
+	hits, err = parseFeedback("gobuster", strings.NewReader(gobusterResults), "FUZZ")
+	assert.NoError(t, err)
+	assert.Equal(t, []feedbackHit{{path: "admin", status: 301}, {path: "backup.zip", status: 200}, {path: "login", status: 403}}, hits)
+
+	_, err = parseFeedback("ffuf-json", strings.NewReader("not json"), "FUZZ")
+	assert.Error(t, err)
+	_, err = parseFeedback("burp", strings.NewReader(""), "FUZZ")
+	assert.EqualError(t, err, `unknown feedback format "burp", valid formats are ffuf-json, feroxbuster-json, gobuster`)
+}
+
+func TestFeedbackHitCandidates(t *testing.T) {
+	assert.Equal(t, []string{"admin"}, feedbackHit{input: "admin", path: "x/admin"}.candidates())
+	assert.Equal(t, []string{"admin/config.php", "config.php", "admin/config", "config"}, feedbackHit{path: "admin/config.php"}.candidates())
+	assert.Equal(t, []string{"api/v1/", "api/v1", "v1"}, feedbackHit{path: "api/v1/"}.candidates())
+	assert.Equal(t, []string{".htaccess"}, feedbackHit{path: ".htaccess"}.candidates())
+	assert.Empty(t, feedbackHit{path: ""}.candidates())
+
+	assert.Equal(t, "config.php", feedbackHit{path: "admin/config.php"}.unknownWord())
+	assert.Equal(t, "v1", feedbackHit{path: "api/v1/"}.unknownWord())
+	assert.Equal(t, "", feedbackHit{path: ""}.unknownWord())
+}
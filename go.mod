@@ -3,14 +3,16 @@ module github.com/proditis/orunmila
 go 1.18
 
 require (
+	github.com/klauspost/compress v1.17.2
 	github.com/mattn/go-sqlite3 v1.14.34
 	github.com/sirupsen/logrus v1.9.4
 	github.com/stretchr/testify v1.11.1
+	github.com/ulikunitz/xz v0.5.12
+	golang.org/x/sys v0.13.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/sys v0.13.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
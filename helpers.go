@@ -1,13 +1,17 @@
 package main
 
 import (
-	"bufio"
-	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/proditis/orunmila/store"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -24,243 +28,91 @@ func check(e error) {
 	}
 }
 
-// Convert tags' ids from the db into a comma separated string
-func TagsToIdsInString() string {
-	var a []string
-	for _, id := range Tags {
-		log.Debugf("[TagsToIdsInString] id => %d\n", id)
-		a = append(a, fmt.Sprint(id))
-	}
-
-	return strings.Join(a, ",")
-}
-
-// Gets the ID of a given tag
-func getTagId(db *sql.DB, tag string) int64 {
-	var id int64
-	err := db.QueryRow("select id from tags where name = ?", tag).Scan(&id)
-	if err != nil {
-		id = -1
-	}
-	return id
-}
-
-// Gets the ID of a given word
-func getWordId(db *sql.DB, word string) int64 {
-	var id int64
-
-	err := db.QueryRow("select id from words where name = ?", word).Scan(&id)
-	if err != nil {
-		id = -1
-	}
-	return id
-}
-
-// Creates the database schema
-func createDB(dbname string) error {
-	db, err := sql.Open("sqlite3", dbname)
-	check(err)
-	defer db.Close()
-
-	sqlStmt := `
-	create table IF NOT EXISTS words (id integer not null primary key AUTOINCREMENT, name text NOT NULL UNIQUE);
-	create table IF NOT EXISTS tags (id integer not null primary key AUTOINCREMENT, name text NOT NULL UNIQUE);
-	create table IF NOT EXISTS wt (word_id integer not null , tag_id integer not null, FOREIGN KEY(word_id) REFERENCES words(id),FOREIGN KEY(tag_id) REFERENCES tags(id),PRIMARY KEY(word_id,tag_id));
-	create table IF NOT EXISTS sysconfig(name text not null primary key, val text);
-	insert or ignore into sysconfig(name,val) values ("version","0.0.0"),("dbname","default");
-	`
-	_, err = db.Exec(sqlStmt)
-	if err != nil {
-		log.Printf("%q: %s\n", err, sqlStmt)
-		return err
-	}
-	return nil
-}
+// Split a comma separated string into a list of unique, non empty, items
+func splitList(inString string) []string {
+	var items []string
+	seen := make(map[string]bool)
 
-// Split a string into a HASH map of the form Array[word]=-1
-func stringToArray(inString string) map[string]int64 {
-	// explode tags by comma
-	var wordsArray = strings.Split(inString, ",")
-	var _tMap = make(map[string]int64)
-
-	// loop through unique items
-	for _, s := range wordsArray {
+	for _, s := range strings.Split(inString, ",") {
 		s = strings.TrimSpace(s)
-		if s != "" && _tMap[s] != -1 {
-			_tMap[s] = -1
+		if s != "" && !seen[s] {
+			seen[s] = true
+			items = append(items, s)
 		}
 	}
 
-	return _tMap
+	return items
 }
 
-// Populate Tags map array with their corresponding id
-// Tags[tag_name]=tag_id
-func populateTagIds(db *sql.DB) {
-	for tag, id := range Tags {
-		if id <= 0 {
-			// fetch tag id from database if exists and assign it
-			Tags[tag] = getTagId(db, tag)
+// Merge lists of items, keeping the first occurrence of each one
+func mergeLists(lists ...[]string) []string {
+	var items []string
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		for _, item := range list {
+			if !seen[item] {
+				seen[item] = true
+				items = append(items, item)
+			}
 		}
 	}
-
+	return items
 }
 
-// Removes tags that have not been assigned an ID
-// Tags[tag_name]=tag_id
-func removeEmptyTags() {
-	for tag, id := range Tags {
-		if id <= 0 {
-			log.Debugln("Removing not found tag:", tag)
-			delete(Tags, tag)
-		}
-	}
-}
+// The layouts accepted by parseTime, dates are in local time
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
 
-// Import the tags into the database and populate Tags with tag_id
-func importTags(db *sql.DB) {
-	tx, err := db.Begin()
-	check(err)
-	tagsStmt, err := tx.Prepare("insert or ignore into tags(name) values(?)")
-	check(err)
-	defer tagsStmt.Close()
+// Units of the relative durations accepted by parseTime on top of the ones of time.ParseDuration
+var durationUnits = map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
 
-	for tag, id := range Tags {
-		if id <= 0 {
-			id = getTagId(db, tag)
-		}
-		if id <= 0 {
-			result, err := tagsStmt.Exec(tag)
-			check(err)
-			id, _ = result.LastInsertId()
-		}
-		Tags[tag] = id
-		log.Println("Found tag id:", id)
+// Parse an absolute time, ie 2026-10-01, or a duration relative to now, ie 7d, 2w or 12h.
+// An empty string returns the zero time.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
 	}
-	err = tx.Commit()
-	check(err)
-}
-
-// Import the words from a given filename into the database
-func importFileWords(db *sql.DB, filename string) {
-
-	importTags(db)
-	log.Println(Tags)
-	file, err := os.Open(filename)
-	check(err)
-	defer file.Close()
-
-	tx, err := db.Begin()
-	check(err)
-
-	wordsStmt, err := tx.Prepare("insert or ignore into words(name) values(?)")
-	check(err)
-	defer wordsStmt.Close()
-
-	wtStmt, err := tx.Prepare("insert or ignore into wt(word_id,tag_id) values(?,?)")
-	check(err)
-	defer wtStmt.Close()
-
-	scanner := bufio.NewScanner(file)
-	var lines = 0
-	for scanner.Scan() {
-		word := strings.TrimSpace(scanner.Text())
-		var word_id int64
-		if word != "" {
-			log.Debugln("importing word:", word)
-			if word_id = getWordId(db, word); word_id <= 0 {
-				result, err := wordsStmt.Exec(word)
-				check(err)
-				word_id, err = result.LastInsertId()
-				check(err)
-				if word_id <= 0 {
-					log.Debugf("word %s already exists, fetching", word)
-					word_id = getWordId(db, word)
-					log.Println("Found word id:", word_id)
-				}
-			}
-			//
-			log.Debugf("word: %s => id: %d\n", word, word_id)
-			for tag, tag_id := range Tags {
-				log.Debugf("adding wt(%d,%d) // %s %s", word_id, tag_id, word, tag)
-				_, err = wtStmt.Exec(word_id, tag_id)
-				if err != nil {
-					log.Error(err)
-				}
-			}
-			word_id = 0
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
 		}
-		if lines%4000 == 0 {
-			log.Info("Lines:", lines)
-			err = tx.Commit()
-			check(err)
-			tx, err = db.Begin()
-			check(err)
-			wordsStmt, err = tx.Prepare("insert or ignore into words(name) values(?)")
-			check(err)
-			defer wordsStmt.Close()
-
-			wtStmt, err = tx.Prepare("insert or ignore into wt(word_id,tag_id) values(?,?)")
-			check(err)
-			defer wtStmt.Close()
-		}
-		lines++
 	}
-	err = tx.Commit()
-	check(err)
-}
-
-// Search for the needle in the haystack
-func containsValue(haystack map[string]int64, needle string) bool {
-	for tagname := range haystack {
-		if tagname == needle {
-			return true
+	if unit, ok := durationUnits[s[len(s)-1:]]; ok {
+		if n, err := strconv.Atoi(s[:len(s)-1]); err == nil && n >= 0 {
+			return now.Add(-time.Duration(n) * unit), nil
 		}
 	}
-	return false
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected a date such as 2026-10-01 or a duration such as 7d, 2w or 12h", s)
 }
 
-//
-// Search for words matching tags
-//
-func searchWordsByTagIds(db *sql.DB, tags string, showTags bool) {
-	queryStr := `select t1.name,(select group_concat(name,',') from tags where id in (select tag_id from wt where word_id=t1.id)) as tagged from words as t1`
-	populateTagIds(db)
-	removeEmptyTags()
-
-	if len(tags) > 0 {
-		userTags := strings.Split(tags, ",")
-		log.Infoln("Using tags:", userTags)
-
-		for _, CurrentUserTag := range userTags {
-			if !containsValue(Tags, CurrentUserTag) {
-				log.Warnf("tag %q not found in the db\n", CurrentUserTag)
-			}
-		}
+// open the database given by -db for reading and writing
+func openStore() *store.Store {
+	s, err := store.Open(*dbPtr)
+	check(err)
+	return s
+}
 
-		queryStr = fmt.Sprintf(queryStr+` left join wt as t2 on t2.word_id=t1.id WHERE t2.tag_id IN (%s) group by t1.id`, TagsToIdsInString())
-	} else {
-		log.Infoln("No tags were given")
+// open the database given by -db for reading only, upgrading its schema first if needed
+func openStoreReadOnly() *store.Store {
+	s, err := store.OpenReadOnly(*dbPtr)
+	if errors.Is(err, store.ErrSchemaOutdated) {
+		log.Debugln("[openStoreReadOnly]", err)
+		openStore().Close()
+		s, err = store.OpenReadOnly(*dbPtr)
 	}
-	rows, err := db.Query(queryStr)
 	check(err)
-	defer rows.Close()
-	for rows.Next() {
-		var name string
-		var tags string
-		err = rows.Scan(&name, &tags)
-		if err != nil {
-			log.Fatal(err)
-		}
-		if showTags {
-			fmt.Println(name, tags)
-		} else {
-			fmt.Println(name)
-		}
+	return s
+}
 
+// open a file for reading, "-" stands for the standard input
+func openInput(filename string) (io.ReadCloser, error) {
+	if filename == "-" {
+		return io.NopCloser(os.Stdin), nil
 	}
-	err = rows.Err()
-	check(err)
+	return os.Open(filename)
 }
 
 // check if file exists
@@ -280,6 +132,56 @@ func isFileExists(filename string) bool {
 func createDbFileifNotExists(dbPtr string) {
 	if !isFileExists(dbPtr) {
 		log.Debugln("database does not exist, creating...")
-		createDB(dbPtr)
+		check(store.Create(dbPtr))
+	}
+}
+
+// subcmdAction is an action of a subcommand made of actions, such as tag ls, along with its aliases
+type subcmdAction struct {
+	names []string
+	run   func(args []string) error
+}
+
+// Parse the args of a subcommand made of actions and run the action they name, logging its errors.
+// The FlagSet is named after the subcommand, its usage lists the actions.
+func runAction(cmd *flag.FlagSet, args []string, actions []subcmdAction) error {
+	err := cmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	if cmd.NArg() == 0 {
+		log.Errorf("[%sSubcmd] you need to provide an action", cmd.Name())
+		cmd.Usage()
+		return fmt.Errorf("no action given")
+	}
+
+	action, args := cmd.Arg(0), cmd.Args()[1:]
+	for _, a := range actions {
+		for _, name := range a.names {
+			if name != action {
+				continue
+			}
+			err = a.run(args)
+			if err != nil && err != flag.ErrHelp {
+				log.Errorf("[%sSubcmd] %v", cmd.Name(), err)
+			}
+			return err
+		}
+	}
+	fmt.Fprintf(cmd.Output(), "Unrecognized %s action: %s\n", cmd.Name(), action)
+	cmd.Usage()
+	return fmt.Errorf("unrecognized %s action %q", cmd.Name(), action)
+}
+
+// Create the FlagSet of an action of a subcommand, usage is the synopsis of the action
+func newActionFlagSet(subcmd, action, usage, description string) *flag.FlagSet {
+	cmd := flag.NewFlagSet(subcmd+" "+action, flag.ContinueOnError)
+	cmd.SetOutput(flag.CommandLine.Output())
+	cmd.Usage = func() {
+		fmt.Fprint(cmd.Output(), description+"\n\n")
+		fmt.Fprintf(cmd.Output(), "Usage of orunmila %s %s:\n", subcmd, action)
+		fmt.Fprintf(cmd.Output(), "orunmila [-db <db_path>] [-debug] %s %s\n\n", subcmd, usage)
+		cmd.PrintDefaults()
 	}
+	return cmd
 }
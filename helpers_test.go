@@ -1,12 +1,11 @@
 package main
 
 import (
-	"database/sql"
 	"flag"
 	"os"
 	"path/filepath"
-	"reflect"
 	"testing"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -45,169 +44,40 @@ func TestIsFileExists(t *testing.T) {
 	}
 }
 
-func TestTagsToIdsInString(t *testing.T) {
-	Tags = make(map[string]int64)
-
-	wants := ""
-	if TagsToIdsInString() != wants {
-		t.Fatal(`Error: TagsToIdsInString not empty`)
-	}
-	Tags["test"] = 1
-	wants = "1"
-	got := TagsToIdsInString()
-	if got != wants {
-		t.Fatalf(`Error: TagsToIdsInString got %s not %s`, got, wants)
-	}
-	Tags["test2"] = -1
-	wants = "1,-1"
-	got = TagsToIdsInString()
-	// hash maps have random order
-	if got != wants && got != "-1,1" {
-		t.Fatalf(`Error: TagsToIdsInString not %v got %v`, wants, got)
-	}
-
-}
-
-func TestGetTagId(t *testing.T) {
-	// requires db access
-	Tags = make(map[string]int64)
-	t.Log(`SOFTFAIL: not implemented`)
-}
-func TestGetWordId(t *testing.T) {
-	// requires db access
-	t.Log(`SOFTFAIL: not implemented`)
-}
-
-func TestCreateDB(t *testing.T) {
-	// cleanup any existing files
-	os.Remove("random.db")
-
-	// create database
-	err := createDB("random.db")
-	if err != nil {
-		t.Fatalf(`Error: createDB failed %v`, err)
-	}
-
-	err = createDB("random.db")
-	if err != nil {
-		t.Fatalf(`Error: 2nd createDB failed, %v`, err)
-	}
-	defer os.Remove("random.db")
-}
-
-func TestStringToArray(t *testing.T) {
-	var wants map[string]int64
-	wants = map[string]int64{
-		"a": -1,
-		"b": -1,
-		"c": -1,
-	}
-	got := stringToArray("a,b,c")
-	if !reflect.DeepEqual(got, wants) {
-		t.Fatalf(`Error: stringToArray wants %v got %v`, wants, got)
-	}
-
-	got = stringToArray("a,b")
-	if reflect.DeepEqual(got, wants) {
-		t.Fatalf(`Error: stringToArray wants %v got %v`, wants, got)
-	}
-
-	got = stringToArray("")
-	wants = map[string]int64{}
-	if !reflect.DeepEqual(got, wants) {
-		t.Fatalf(`Error: stringToArray wants %v got %v`, wants, got)
-	}
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, splitList("a,b,c"))
+	assert.Equal(t, []string{"a", "b"}, splitList(" a, b ,a,,"))
+	assert.Nil(t, splitList(""))
 }
 
-func TestPopulateTagIds(t *testing.T) {
-	t.Log(`SOFTFAIL: not implemented`)
+func TestMergeLists(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, mergeLists([]string{"a", "b"}, nil, []string{"b", "c", "a"}))
+	assert.Nil(t, mergeLists())
 }
-func TestRemoveEmptyTags(t *testing.T) {
-	var wants map[string]int64
-	wants = map[string]int64{}
-	Tags = map[string]int64{
-		"a": -1,
-		"b": -1,
-		"c": -1,
-	}
-	removeEmptyTags()
-	if !reflect.DeepEqual(Tags, wants) {
-		t.Fatalf(`Error: removeEmptyTags want %v got %v`, wants, Tags)
-	}
 
-	wants = map[string]int64{"b": 1}
-	Tags = map[string]int64{
-		"a": -1,
-		"b": 1,
-		"c": -1,
-	}
-	removeEmptyTags()
-	if !reflect.DeepEqual(Tags, wants) {
-		t.Fatalf(`Error: removeEmptyTags want %v got %v`, wants, Tags)
+func TestParseTime(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"":                     {},
+		"7d":                   now.Add(-7 * 24 * time.Hour),
+		"2w":                   now.Add(-14 * 24 * time.Hour),
+		"90m":                  now.Add(-90 * time.Minute),
+		"1h30m":                now.Add(-90 * time.Minute),
+		"2026-10-01":           time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local),
+		"2026-10-01 08:30:00":  time.Date(2026, 10, 1, 8, 30, 0, 0, time.Local),
+		"2026-10-01T08:30:00Z": time.Date(2026, 10, 1, 8, 30, 0, 0, time.UTC),
+	}
+	for input, want := range tests {
+		got, err := parseTime(input, now)
+		if assert.NoErrorf(t, err, `parseTime(%q)`, input) {
+			assert.Truef(t, want.Equal(got), `parseTime(%q) = %v, want %v`, input, got, want)
+		}
+	}
+	for _, input := range []string{"yesterday", "-7d", "7x", "d"} {
+		_, err := parseTime(input, now)
+		assert.Errorf(t, err, `parseTime(%q)`, input)
 	}
 }
-func TestImportTags(t *testing.T) {
-	Tags = make(map[string]int64)
-	Tags = map[string]int64{
-		"a": -1,
-		"b": -1,
-		"c": -1,
-	}
-
-	createDbFileifNotExists("random.db")
-	defer os.Remove("random.db")
-
-	db, err := sql.Open("sqlite3", "random.db")
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer db.Close()
-
-	importTags(db)
-
-	for k, v := range Tags {
-		assert.Greaterf(t, int64(v), int64(-1), `importTags: failed initial insert for key %v has id %v`, k, v)
-	}
-
-	Tags = map[string]int64{
-		"a": -1,
-		"b": -1,
-		"c": -1,
-	}
-	importTags(db)
-	for k, v := range Tags {
-		assert.Greaterf(t, int64(v), int64(-1), `importTags: failed to populate key %v has id %v`, k, v)
-	}
-
-	Tags = map[string]int64{
-		"a": -1,
-		"b": -1,
-		"c": -1,
-		"d": -1,
-	}
-
-	importTags(db)
-	for k, v := range Tags {
-		assert.Greaterf(t, int64(v), int64(-1), `importTags: populate existing and insert last\nkey %v has id %v`, k, v)
-	}
-
-}
-func TestImportFileWords(t *testing.T) {
-	t.Log(`SOFTFAIL: not implemented`)
-}
-func TestSearchWordsByTagIds(t *testing.T) {
-	// populate words
-	// populate tags
-	// populate word_tags
-	var dbname = "random.db"
-	createDbFileifNotExists(dbname)
-	defer os.Remove(dbname)
-	db, err := sql.Open("sqlite3", dbname)
-	assert.NoError(t, err)
-
-	searchWordsByTagIds(db, "a", true)
-	t.Log(`SOFTFAIL: not implemented`)
-}
 
 func TestCreateDbFileifNotExists(t *testing.T) {
 	var dbname = "random.db"
@@ -224,21 +94,28 @@ func TestCreateDbFileifNotExists(t *testing.T) {
 	}
 }
 
-func TestContainsValue(t *testing.T) {
-	var haystack map[string]int64
-	assert := assert.New(t)
-	wants := false
-	needle := "notexist"
-	got := containsValue(haystack, needle)
-	assert.Falsef(got, `containsValue haystack %v, needle %v, wants %v got %v`, haystack, needle, wants, got)
-
-	haystack = map[string]int64{"exist": 1}
-	got = containsValue(haystack, needle)
-	assert.Falsef(got, `containsValue haystack %v, needle %v, wants %v got %v`, haystack, needle, wants, got)
-
-	needle = "exist"
-	got = containsValue(haystack, needle)
-	wants = true
-	assert.Truef(got, `containsValue haystack %v, needle %v, wants %v got %v`, haystack, needle, wants, got)
-
+func TestActionSubcmdUsage(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		subcmd func([]string) error
+		action string
+	}{
+		{"tag", tagSubcmd, "ls"},
+		{"word", wordSubcmd, "rm"},
+		{"batches", batchesSubcmd, "undo"},
+		{"checkpoint", checkpointSubcmd, "reset"},
+		{"ext", extSubcmd, "set"},
+		{"techmap", techmapSubcmd, "set"},
+	} {
+		assert.EqualError(t, test.subcmd([]string{}), `no action given`, test.name)
+		buf.Reset()
+		assert.EqualError(t, test.subcmd([]string{"frobnicate"}), `unrecognized `+test.name+` action "frobnicate"`)
+		assert.Contains(t, buf.String(), "Unrecognized "+test.name+" action: frobnicate")
+		assert.Contains(t, buf.String(), "Usage of orunmila "+test.name+":")
+
+		buf.Reset()
+		assert.Equal(t, flag.ErrHelp, test.subcmd([]string{test.action, "-help"}), test.name)
+		assert.Contains(t, buf.String(), "Usage of orunmila "+test.name+" "+test.action+":")
+		assert.NotContains(t, buf.String(), "level=error", "asking for help is not an error")
+	}
 }
@@ -1,11 +1,15 @@
 package main
 
 import (
-	"database/sql"
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/proditis/orunmila/store"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -16,20 +20,41 @@ func importSubcmd(args []string) {
 	importCmd.SetOutput(flag.CommandLine.Output())
 
 	importCmd.Usage = func() {
-		fmt.Fprint(importCmd.Output(), "Import a word file into the database with optional tags\n\n")
+		fmt.Fprint(importCmd.Output(), "Import a word file into the database with optional tags\n")
+		fmt.Fprint(importCmd.Output(), "Files compressed with gzip, bzip2, xz or zstd are decompressed on the fly\n\n")
 		fmt.Fprintln(importCmd.Output(), "Usage of orunmila import:")
 		fmt.Fprintf(importCmd.Output(), "orunmila [-db <db_path>] [-debug] import -tags [tag]... [filesname]...\n\n")
-		fmt.Fprintln(importCmd.Output(), "  filename\n\tthe filename(s) to read the words from")
+		fmt.Fprintln(importCmd.Output(), "  filename\n\tthe filename(s) to read the words from, - reads from the standard input")
 		importCmd.PrintDefaults()
+		fmt.Fprintln(importCmd.Output(), "\texample: orunmila import -format tagged -tags curated curated.txt")
+		fmt.Fprintln(importCmd.Output(), "\texample: orunmila import -r -tags-from-path -tag-ignore discovery -tag-map web-content=web SecLists/Discovery")
+		fmt.Fprintln(importCmd.Output(), "\texample: orunmila import -undo 42")
 	}
 
 	var (
-		tagsPtr = importCmd.String("tags", "", "a comma separated list of the tags to use")
+		tagsPtr         = importCmd.String("tags", "", "a comma separated list of the tags to use")
+		recursivePtr    = importCmd.Bool("r", false, "import the files of the given directories recursively")
+		tagsFromPathPtr = importCmd.Bool("tags-from-path", false, "derive extra tags from the directories and the name of each file")
+		tagMapPtr       = importCmd.String("tag-map", "", "a comma separated list of from=to renames for the path derived tags, an empty to drops the tag")
+		tagIgnorePtr    = importCmd.String("tag-ignore", "", "a comma separated list of path components that should not become tags")
+		includePtr      = importCmd.String("include", "", "a comma separated list of filename patterns to import from directories, ie *.txt (default: all files)")
+		formatPtr       = importCmd.String("format", store.FormatPlain, "the format of the files, plain for one word per line, tagged for lines of word<delim>tag1,tag2")
+		delimPtr        = importCmd.String("delim", `\t`, "the delimiter between the word and its tags of the tagged format")
+		sourcePtr       = importCmd.String("source", "", "the source recorded for the imported words, ie the URL a list was downloaded from (default: the filename)")
+		undoPtr         = importCmd.Int64("undo", 0, "remove the words and associations introduced by a batch instead of importing, see orunmila batches ls")
+		batchPtr        = importCmd.Int("batch", store.DefaultBatchSize, "the number of lines committed per transaction")
 	)
 
 	importCmd.Parse(args)
 
-	if len(args) == 0 {
+	if *undoPtr != 0 {
+		db := openStore()
+		defer db.Close()
+		check(undoBatch(db, *undoPtr))
+		return
+	}
+
+	if importCmd.NArg() == 0 {
 		log.Error("[importSubcmd] you need to provide at least a filename")
 		importCmd.Usage()
 		os.Exit(1)
@@ -37,19 +62,138 @@ func importSubcmd(args []string) {
 
 	log.Println("performing an import on the given files:", importCmd.Args())
 
-	dsn := fmt.Sprintf("file:%s?mode=rw", *dbPtr)
-	db, err := sql.Open("sqlite3", dsn)
-	check(err)
+	var tagger *pathTagger
+	var err error
+	if *tagsFromPathPtr {
+		tagger, err = newPathTagger(*tagMapPtr, *tagIgnorePtr)
+		check(err)
+	}
+	include := splitList(*includePtr)
+
+	delimiter, err := strconv.Unquote(`"` + *delimPtr + `"`)
+	if err != nil {
+		delimiter = *delimPtr
+	}
+	opts := store.ImportOptions{Tags: splitList(*tagsPtr), Format: *formatPtr, Delimiter: delimiter, BatchSize: *batchPtr, Source: *sourcePtr}
+
+	db := openStore()
 	defer db.Close()
 
-	Tags = stringToArray(*tagsPtr)
+	batch := &importBatch{command: strings.TrimSpace("import " + strings.Join(args, " "))}
+	for _, filename := range importCmd.Args() {
+		if filename == "-" {
+			log.Println("[importSubcmd] importing from the standard input")
+			importFileWords(db, filename, opts, batch)
+			continue
+		}
+		info, err := os.Stat(filename)
+		if os.IsNotExist(err) {
+			log.Warnf("[importSubcmd] %q does not exists.", filename)
+			continue
+		}
+		check(err)
+		if info.IsDir() {
+			if !*recursivePtr {
+				log.Warnf("[importSubcmd] %q is a directory, use -r to import it recursively.", filename)
+				continue
+			}
+			importDirectory(db, filename, opts, batch, tagger, include)
+			continue
+		}
+		log.Println("[importSubcmd] importing file:", filename)
+		fileOpts := opts
+		if tagger != nil {
+			fileOpts.Tags = mergeLists(opts.Tags, tagger.tags(filepath.Base(filename)))
+		}
+		importFileWords(db, filename, fileOpts, batch)
+	}
+	if batch.id == 0 {
+		log.Warnln("[importSubcmd] nothing was imported")
+	}
+}
 
-	for i := 0; i < importCmd.NArg(); i++ {
-		log.Println("[importSubcmd] importing file:", importCmd.Arg(i))
-		if isFileExists(importCmd.Arg(i)) {
-			importFileWords(db, importCmd.Arg(i))
-		} else {
-			log.Warnf("[importSubcmd] %q does not exists.", importCmd.Arg(i))
+// importBatch is the batch of an import run, created along with the first file opened so that
+// a run that imports nothing leaves no empty batch behind
+type importBatch struct {
+	command string
+	id      int64
+}
+
+// The id of the batch, creating it on the first call
+func (b *importBatch) get(db *store.Store) (int64, error) {
+	if b.id == 0 {
+		id, err := db.NewBatch(b.command)
+		if err != nil {
+			return 0, err
 		}
+		b.id = id
+		log.Infoln("[importSubcmd] batch id:", b.id)
 	}
+	return b.id, nil
+}
+
+// Import every regular file found under root, skipping hidden files and directories
+func importDirectory(db *store.Store, root string, opts store.ImportOptions, batch *importBatch, tagger *pathTagger, include []string) {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !matchesAny(d.Name(), include) {
+			return nil
+		}
+
+		fileOpts := opts
+		if tagger != nil {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			fileOpts.Tags = mergeLists(opts.Tags, tagger.tags(rel))
+		}
+		log.Printf("[importDirectory] importing file: %s with tags %v", path, fileOpts.Tags)
+		importFileWords(db, path, fileOpts, batch)
+		return nil
+	})
+	check(err)
+}
+
+// Check if a filename matches any of the given patterns, an empty list matches everything
+func matchesAny(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Import the words from a given filename, or the standard input for "-", into the database,
+// under the batch of the run when there is one
+func importFileWords(db *store.Store, filename string, opts store.ImportOptions, batch *importBatch) {
+	file, err := openInput(filename)
+	check(err)
+	defer file.Close()
+
+	if batch != nil {
+		opts.BatchID, err = batch.get(db)
+		check(err)
+	}
+	if opts.Source == "" {
+		opts.Source = filename
+		if filename == "-" {
+			opts.Source = "<stdin>"
+		}
+	}
+	count, err := db.ImportReader(file, opts)
+	check(err)
+	log.Infof("[importFileWords] imported %d words from %s", count, filename)
 }
@@ -1,9 +1,149 @@
 package main
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/proditis/orunmila/store"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestImportSubcmd(t *testing.T) {
 	t.Log(`SOFTFAIL: No idea how to pass custom arguments to subcmds`)
 }
+
+func TestImportFileWords(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	db := openStore()
+	defer db.Close()
+
+	importFileWords(db, "lista.txt", store.ImportOptions{Tags: []string{"lista"}}, nil)
+	importFileWords(db, "listb.txt", store.ImportOptions{Tags: []string{"listb"}}, nil)
+
+	var words, links int64
+	assert.NoError(t, db.DB().QueryRow("select count(*) from words").Scan(&words))
+	assert.NoError(t, db.DB().QueryRow("select count(*) from wt").Scan(&links))
+	assert.Equal(t, int64(7), words)
+	assert.Equal(t, int64(8), links)
+}
+
+func TestImportFileWordsStdin(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	db := openStore()
+	defer db.Close()
+
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	go func() {
+		w.WriteString("piped1\npiped2\n")
+		w.Close()
+	}()
+	importFileWords(db, "-", store.ImportOptions{Tags: []string{"stdin"}}, nil)
+
+	tag, err := db.Tag("stdin")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), tag.Words)
+}
+
+func TestImportDirectory(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+
+	root := t.TempDir()
+	files := map[string]string{
+		"Discovery/Web-Content/CMS/wordpress.txt": "wp-admin\nwp-login.php\n",
+		"Discovery/Web-Content/CMS/drupal.txt":    "node\nwp-admin\n",
+		"Discovery/Web-Content/raft-small.txt":    "admin\n",
+		"Discovery/README.md":                     "# readme\n",
+		"Discovery/.git/config":                   "[core]\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	importSubcmd([]string{"-r", "-tags", "seclists", "-tags-from-path", "-tag-ignore", "discovery", "-tag-map", "web-content=web", "-include", "*.txt", filepath.Join(root, "Discovery")})
+	// directories are skipped without -r, along with the missing files, and no batch is left behind
+	importSubcmd([]string{"-tags", "skipped", root, filepath.Join(root, "missing.txt")})
+
+	db := openStore()
+	defer db.Close()
+	batches, err := db.Batches()
+	assert.NoError(t, err)
+	if assert.Len(t, batches, 1) {
+		assert.Contains(t, batches[0].Command, "-tags-from-path")
+	}
+
+	tags, err := db.Tags()
+	assert.NoError(t, err)
+	counts := make(map[string]int64)
+	for _, tag := range tags {
+		counts[tag.Name] = tag.Words
+	}
+	assert.Equal(t, map[string]int64{"seclists": 4, "web": 4, "cms": 3, "wordpress": 2, "drupal": 2, "raft-small": 1}, counts)
+
+	_, err = db.Word("# readme")
+	assert.Error(t, err, `files not matching -include should be skipped`)
+}
+
+func TestImportTaggedRoundTrip(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+
+	input := filepath.Join(t.TempDir(), "curated.txt")
+	assert.NoError(t, os.WriteFile(input, []byte("admin\tphp,drupal\nlogin|x\tphp\nbare\n"), 0644))
+	importSubcmd([]string{"-format", "tagged", "-tags", "curated", input})
+
+	db := openStore()
+	var out bytes.Buffer
+	w, _ := newResultWriter("tagged", &out, false)
+	assert.NoError(t, searchWords(db, store.SearchOptions{}, w))
+	db.Close()
+	assert.Equal(t, "admin\tcurated,drupal,php\nlogin|x\tcurated,php\nbare\tcurated\n", out.String())
+
+	// a database round-trips through a single text file
+	export := filepath.Join(t.TempDir(), "export.txt")
+	assert.NoError(t, os.WriteFile(export, out.Bytes(), 0644))
+	assert.NoError(t, os.Remove(*dbPtr))
+	createDbFileifNotExists(*dbPtr)
+	importSubcmd([]string{"-format", "tagged", "-delim", "\\t", export})
+
+	db = openStore()
+	defer db.Close()
+	var again bytes.Buffer
+	w, _ = newResultWriter("tagged", &again, false)
+	assert.NoError(t, searchWords(db, store.SearchOptions{}, w))
+	assert.Equal(t, out.String(), again.String())
+}
+
+func TestImportFileWordsSources(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	db := openStore()
+	defer db.Close()
+
+	importFileWords(db, "lista.txt", store.ImportOptions{Tags: []string{"lista"}}, nil)
+	importFileWords(db, "listb.txt", store.ImportOptions{Tags: []string{"listb"}, Source: "https://example.com/listb.txt"}, nil)
+
+	var out bytes.Buffer
+	w, _ := newResultWriter("plain", &out, false)
+	assert.NoError(t, searchWords(db, store.SearchOptions{Sources: []string{"https://example.com/*"}}, w))
+	assert.Equal(t, int64(4), int64(strings.Count(out.String(), "\n")))
+
+	info, err := db.Word("common1")
+	assert.NoError(t, err)
+	if assert.Len(t, info.Sources, 2) {
+		assert.Equal(t, "lista.txt", info.Sources[0].Path)
+		assert.Equal(t, "https://example.com/listb.txt", info.Sources[1].Path)
+	}
+}
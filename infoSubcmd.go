@@ -1,11 +1,8 @@
 package main
 
 import (
-	"database/sql"
 	"flag"
 	"fmt"
-
-	log "github.com/sirupsen/logrus"
 )
 
 // parse args of the info subcommand and exec it
@@ -24,24 +21,13 @@ func infoSubcmd(args []string) {
 	// nothing to parse, just there to trigger the usage menu
 	infoCmd.Parse(args)
 
-	dsn := fmt.Sprintf("file:%s?mode=rw", *dbPtr)
-	db, err := sql.Open("sqlite3", dsn)
-	check(err)
+	db := openStore()
 	defer db.Close()
 
-	rows, err := db.Query("SELECT * FROM sysconfig")
+	settings, err := db.Info()
 	check(err)
-	defer rows.Close()
-
-	for rows.Next() {
-		var name string
-		var val string
-		err = rows.Scan(&name, &val)
-		if err != nil {
-			log.Fatal(err)
-		}
-		fmt.Printf("[%s]: %s\n", name, val)
+
+	for _, setting := range settings {
+		fmt.Printf("[%s]: %s\n", setting.Name, setting.Value)
 	}
-	err = rows.Err()
-	check(err)
 }
@@ -5,13 +5,9 @@ import (
 	"fmt"
 
 	log "github.com/sirupsen/logrus"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 var (
-	Tags     = make(map[string]int64)
-	Words    = make(map[string]int64)
 	dbPtr    *string
 	debugPtr *bool
 )
@@ -22,12 +18,24 @@ func main() {
 		fmt.Fprintf(flag.CommandLine.Output(), "Usage of orunmila:\n")
 		flag.PrintDefaults()
 		fmt.Fprintln(flag.CommandLine.Output(), "\nSubcommands")
-		fmt.Fprintln(flag.CommandLine.Output(), "  add      Add words into the database with optional tags")
-		fmt.Fprintln(flag.CommandLine.Output(), "  search   Searches the database for given words")
-		fmt.Fprintln(flag.CommandLine.Output(), "  import   Import a wordlist file into the database")
-		fmt.Fprintln(flag.CommandLine.Output(), "  info     Display database system configuration information")
-		fmt.Fprintln(flag.CommandLine.Output(), "  describe Set the database description")
-		fmt.Fprintln(flag.CommandLine.Output(), "  vacuum   Rebuild the database file, repacking it into a minimal amount of disk space")
+		fmt.Fprintln(flag.CommandLine.Output(), "  add        Add words into the database with optional tags")
+		fmt.Fprintln(flag.CommandLine.Output(), "  search     Searches the database for given words")
+		fmt.Fprintln(flag.CommandLine.Output(), "  tui        Pick tags interactively, previewing the matching words, and write the list")
+		fmt.Fprintln(flag.CommandLine.Output(), "  combine    Display the cross product of lists of words, ie api/v1/users")
+		fmt.Fprintln(flag.CommandLine.Output(), "  import     Import a wordlist file into the database")
+		fmt.Fprintln(flag.CommandLine.Output(), "  feedback   Credit the words found by ffuf, feroxbuster or gobuster")
+		fmt.Fprintln(flag.CommandLine.Output(), "  recommend  Build wordlists out of the technologies detected by httpx or Wappalyzer")
+		fmt.Fprintln(flag.CommandLine.Output(), "  techmap    Manage the mapping of technologies to tags of recommend")
+		fmt.Fprintln(flag.CommandLine.Output(), "  serve      Serve the database over an HTTP API and a web UI")
+		fmt.Fprintln(flag.CommandLine.Output(), "  info       Display database system configuration information")
+		fmt.Fprintln(flag.CommandLine.Output(), "  tag        List, rename, merge, delete and describe tags")
+		fmt.Fprintln(flag.CommandLine.Output(), "  word       Show, untag and remove words")
+		fmt.Fprintln(flag.CommandLine.Output(), "  batches    List and undo import and add runs")
+		fmt.Fprintln(flag.CommandLine.Output(), "  checkpoint List and reset the checkpoints of incremental searches")
+		fmt.Fprintln(flag.CommandLine.Output(), "  ext        Manage the extension sets of search -ext")
+		fmt.Fprintln(flag.CommandLine.Output(), "  describe   Set the database description")
+		fmt.Fprintln(flag.CommandLine.Output(), "  vacuum     Apply schema updates and rebuild the database file, repacking it into a minimal amount of disk space")
+		fmt.Fprintln(flag.CommandLine.Output(), "  completion Print the bash, zsh or fish completion script")
 	}
 	exitCode := 0
 	var err error
@@ -50,27 +58,56 @@ func main() {
 		subcommand, args = args[0], args[1:]
 	}
 
-	// poor guy, for now
-	//log.Debugln("using words:", *wordsPtr)
 	log.Debugln("[main] using db:", *dbPtr)
 	log.Debugln("[main] debug mode:", *debugPtr)
 
-	createDbFileifNotExists(*dbPtr)
+	// the completion never creates the database
+	switch subcommand {
+	case "completion":
+		if completionSubcmd(args) != nil {
+			exitCode = 2
+		}
+		log.Exit(exitCode)
+	case completeCmdName:
+		completeSubcmd(args)
+		log.Exit(exitCode)
+	}
 
-	// poor guy, for now
-	// Words = stringToArray(*wordsPtr)
+	createDbFileifNotExists(*dbPtr)
 
 	switch subcommand {
 	case "add", "a":
 		addSubcmd(args)
 	case "describe", "des", "d":
 		describeSubcmd(args)
+	case "feedback", "fb":
+		err = feedbackSubcmd(args)
+	case "recommend", "rec", "r":
+		err = recommendSubcmd(args)
+	case "techmap", "tm":
+		err = techmapSubcmd(args)
+	case "serve":
+		err = serveSubcmd(args)
 	case "info":
 		infoSubcmd(args)
+	case "tui":
+		err = tuiSubcmd(args)
+	case "combine", "comb", "c":
+		err = combineSubcmd(args)
 	case "import", "imp", "i":
 		importSubcmd(args)
 	case "search", "sea", "s":
 		err = searchSubcmd(args)
+	case "tag", "tags", "t":
+		err = tagSubcmd(args)
+	case "word", "words", "w":
+		err = wordSubcmd(args)
+	case "batches", "batch", "b":
+		err = batchesSubcmd(args)
+	case "checkpoint", "checkpoints", "cp":
+		err = checkpointSubcmd(args)
+	case "ext", "exts", "e":
+		err = extSubcmd(args)
 	case "vacuum", "vac", "v":
 		vacuumSubcmd(args)
 	default:
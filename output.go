@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/proditis/orunmila/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// The output formats supported by search
+var outputFormats = []string{"plain", "json", "ndjson", "csv", "tsv", "tagged"}
+
+// resultWriter writes search results in a given format
+type resultWriter interface {
+	Write(rec store.Record) error
+	// Close flushes any buffered output, it does not close the underlying writer
+	Close() error
+}
+
+// candidateSet remembers the candidates written so far to drop the duplicates.
+type candidateSet map[string]struct{}
+
+// Add a candidate to the set, returning false when it was already there
+func (c candidateSet) add(candidate string) bool {
+	if _, ok := c[candidate]; ok {
+		return false
+	}
+	c[candidate] = struct{}{}
+	return true
+}
+
+// expandWriter expands the records on their way to another writer, dropping the duplicate candidates.
+// Every expansion is applied to the candidates of the previous one, so that a single set of the
+// candidates is kept whatever the number of expansions.
+type expandWriter struct {
+	next       resultWriter
+	expansions []func(string) []string
+	seen       candidateSet
+	candidates int64
+	duplicates int64
+}
+
+func newExpandWriter(next resultWriter, expansions ...func(string) []string) *expandWriter {
+	return &expandWriter{next: next, expansions: expansions, seen: make(candidateSet)}
+}
+
+func (e *expandWriter) Write(rec store.Record) error {
+	words := []string{rec.Word}
+	for _, expand := range e.expansions {
+		var expanded []string
+		for _, word := range words {
+			expanded = append(expanded, expand(word)...)
+		}
+		words = expanded
+	}
+	for _, candidate := range words {
+		if !e.seen.add(candidate) {
+			e.duplicates++
+			continue
+		}
+		e.candidates++
+
+		out := rec
+		out.Word = candidate
+		if err := e.next.Write(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *expandWriter) Close() error {
+	log.Infof("[expandWriter] generated %d candidates, %d duplicates dropped", e.candidates, e.duplicates)
+	return e.next.Close()
+}
+
+// Create a resultWriter for the given format
+func newResultWriter(format string, w io.Writer, showTags bool) (resultWriter, error) {
+	switch strings.ToLower(format) {
+	case "", "plain":
+		return &plainWriter{w: bufio.NewWriter(w), showTags: showTags}, nil
+	case "json":
+		return &jsonWriter{w: bufio.NewWriter(w)}, nil
+	case "ndjson":
+		bw := bufio.NewWriter(w)
+		return &ndjsonWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+	case "csv":
+		return newDelimitedWriter(w, ','), nil
+	case "tsv":
+		return newDelimitedWriter(w, '\t'), nil
+	case "tagged":
+		return &taggedWriter{w: bufio.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, valid formats are %s", format, strings.Join(outputFormats, ", "))
+}
+
+// plainWriter prints one word per line, optionally followed by its tags
+type plainWriter struct {
+	w        *bufio.Writer
+	showTags bool
+}
+
+func (p *plainWriter) Write(rec store.Record) error {
+	var err error
+	if p.showTags {
+		_, err = fmt.Fprintln(p.w, rec.Word, strings.Join(rec.Tags, ","))
+	} else {
+		_, err = fmt.Fprintln(p.w, rec.Word)
+	}
+	return err
+}
+
+func (p *plainWriter) Close() error {
+	return p.w.Flush()
+}
+
+// jsonWriter streams the records as a single JSON array
+type jsonWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+func (j *jsonWriter) Write(rec store.Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if j.count == 0 {
+		sep = "[\n"
+	}
+	j.count++
+	if _, err = j.w.WriteString(sep); err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	closing := "\n]\n"
+	if j.count == 0 {
+		closing = "[]\n"
+	}
+	if _, err := j.w.WriteString(closing); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
+
+// ndjsonWriter prints one JSON object per line
+type ndjsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(rec store.Record) error {
+	return n.enc.Encode(rec)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.w.Flush()
+}
+
+// delimitedWriter prints CSV or TSV rows with a header line
+type delimitedWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newDelimitedWriter(w io.Writer, comma rune) *delimitedWriter {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	return &delimitedWriter{w: cw}
+}
+
+func (d *delimitedWriter) writeHeader() error {
+	if d.header {
+		return nil
+	}
+	d.header = true
+	return d.w.Write([]string{"word", "tags", "id"})
+}
+
+func (d *delimitedWriter) Write(rec store.Record) error {
+	if err := d.writeHeader(); err != nil {
+		return err
+	}
+	return d.w.Write([]string{rec.Word, strings.Join(rec.Tags, ","), strconv.FormatInt(rec.ID, 10)})
+}
+
+func (d *delimitedWriter) Close() error {
+	if err := d.writeHeader(); err != nil {
+		return err
+	}
+	d.w.Flush()
+	return d.w.Error()
+}
+
+// taggedWriter prints the words in the tagged import format, word<TAB>tag1,tag2
+type taggedWriter struct {
+	w *bufio.Writer
+}
+
+func (t *taggedWriter) Write(rec store.Record) error {
+	_, err := fmt.Fprintln(t.w, store.FormatTaggedLine(rec.Word, rec.Tags, store.DefaultDelimiter))
+	return err
+}
+
+func (t *taggedWriter) Close() error {
+	return t.w.Flush()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/proditis/orunmila/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var _testRecords = []store.Record{
+	{Word: "admin", Tags: []string{"a", "b"}, ID: 1},
+	{Word: "two words", Tags: []string{}, ID: 2},
+	{Word: `quo"te`, Tags: []string{"c"}, ID: 3},
+}
+
+func writeRecords(t *testing.T, format string, showTags bool, records []store.Record) string {
+	var out bytes.Buffer
+	w, err := newResultWriter(format, &out, showTags)
+	assert.NoError(t, err)
+	for _, rec := range records {
+		assert.NoError(t, w.Write(rec))
+	}
+	assert.NoError(t, w.Close())
+	return out.String()
+}
+
+func TestNewResultWriterUnknown(t *testing.T) {
+	_, err := newResultWriter("xml", &bytes.Buffer{}, false)
+	assert.EqualError(t, err, `unknown output format "xml", valid formats are plain, json, ndjson, csv, tsv, tagged`)
+}
+
+func TestPlainWriter(t *testing.T) {
+	assert.Equal(t, "admin\ntwo words\nquo\"te\n", writeRecords(t, "plain", false, _testRecords))
+	assert.Equal(t, "admin a,b\ntwo words \nquo\"te c\n", writeRecords(t, "", true, _testRecords))
+}
+
+func TestJSONWriter(t *testing.T) {
+	wants := `[
+{"word":"admin","tags":["a","b"],"id":1},
+{"word":"two words","tags":[],"id":2},
+{"word":"quo\"te","tags":["c"],"id":3}
+]
+`
+	assert.Equal(t, wants, writeRecords(t, "json", false, _testRecords))
+	assert.Equal(t, "[]\n", writeRecords(t, "json", false, nil))
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	wants := `{"word":"admin","tags":["a","b"],"id":1}
+{"word":"two words","tags":[],"id":2}
+{"word":"quo\"te","tags":["c"],"id":3}
+`
+	assert.Equal(t, wants, writeRecords(t, "ndjson", false, _testRecords))
+	assert.Equal(t, "", writeRecords(t, "ndjson", false, nil))
+}
+
+func TestDelimitedWriter(t *testing.T) {
+	wants := "word,tags,id\nadmin,\"a,b\",1\ntwo words,,2\n\"quo\"\"te\",c,3\n"
+	assert.Equal(t, wants, writeRecords(t, "csv", false, _testRecords))
+
+	wants = "word\ttags\tid\nadmin\ta,b\t1\ntwo words\t\t2\n\"quo\"\"te\"\tc\t3\n"
+	assert.Equal(t, wants, writeRecords(t, "TSV", false, _testRecords))
+
+	assert.Equal(t, "word,tags,id\n", writeRecords(t, "csv", false, nil))
+}
+
+func TestTaggedWriter(t *testing.T) {
+	wants := "admin\ta,b\ntwo words\nquo\"te\tc\n"
+	assert.Equal(t, wants, writeRecords(t, "tagged", false, _testRecords))
+}
+
+func TestExpandWriter(t *testing.T) {
+	rl, err := parseRule("c")
+	assert.NoError(t, err)
+	pattern, err := parseBackupPattern("{file}~")
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	next, _ := newResultWriter("plain", &out, true)
+	// every expansion applies to the candidates of the previous one, the duplicates are dropped once
+	w := newExpandWriter(next, ruleExpansion([]rule{rl}), extExpansion([]string{"php"}), backupExpansion([]backupPattern{pattern}))
+	assert.NoError(t, w.Write(store.Record{Word: "admin", Tags: []string{"a"}, ID: 1}))
+	assert.NoError(t, w.Write(store.Record{Word: "Admin", Tags: []string{"b"}, ID: 2}))
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "Admin a\nAdmin.php a\nAdmin.php~ a\n", out.String())
+	assert.Equal(t, int64(3), w.candidates)
+	assert.Equal(t, int64(3), w.duplicates)
+
+	// without expansions the records go through as they are
+	out.Reset()
+	w = newExpandWriter(next)
+	assert.NoError(t, w.Write(store.Record{Word: "admin", Tags: []string{"a"}, ID: 1}))
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "admin a\n", out.String())
+}
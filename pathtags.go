@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Extensions of compressed files, stripped before the file stem is turned into a tag
+var compressedExts = []string{".gz", ".bz2", ".xz", ".zst", ".zstd"}
+
+// pathTagger derives tags from the directories and the stem of a file path
+type pathTagger struct {
+	// mapping renames path components, an empty value drops the component
+	mapping map[string]string
+	// ignore lists the path components that do not produce tags
+	ignore map[string]bool
+}
+
+// Create a pathTagger out of a comma separated list of from=to mappings and
+// a comma separated list of components to ignore
+func newPathTagger(mapping string, ignore string) (*pathTagger, error) {
+	p := &pathTagger{mapping: make(map[string]string), ignore: make(map[string]bool)}
+	for _, item := range splitList(mapping) {
+		from, to, found := strings.Cut(item, "=")
+		if !found || normalizeTag(from) == "" {
+			return nil, fmt.Errorf("invalid tag mapping %q, expected from=to", item)
+		}
+		p.mapping[normalizeTag(from)] = normalizeTag(to)
+	}
+	for _, item := range splitList(ignore) {
+		p.ignore[normalizeTag(item)] = true
+	}
+	return p, nil
+}
+
+// Turn a path component into a tag, lowercase with dashes instead of spaces and underscores
+func normalizeTag(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.NewReplacer(" ", "-", "_", "-", ",", "-").Replace(s)
+	return strings.Trim(s, "-")
+}
+
+// Strip the compression and wordlist extensions of a filename
+func fileStem(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range compressedExts {
+		if strings.HasSuffix(lower, ext) {
+			name = name[:len(name)-len(ext)]
+			break
+		}
+	}
+	if ext := filepath.Ext(name); ext != "" && ext != name && isAlphanumeric(ext[1:]) {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name
+}
+
+// Check if a string only holds ASCII letters and digits
+func isAlphanumeric(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Derive the tags of a file from its path, relative to the directory being imported
+func (p *pathTagger) tags(path string) []string {
+	dir, file := filepath.Split(filepath.Clean(path))
+	components := strings.Split(filepath.ToSlash(dir), "/")
+	components = append(components, fileStem(file))
+
+	var tags []string
+	seen := make(map[string]bool)
+	for _, component := range components {
+		if component == "" || component == "." || component == ".." {
+			continue
+		}
+		tag := normalizeTag(component)
+		if p.ignore[tag] {
+			continue
+		}
+		if mapped, ok := p.mapping[tag]; ok {
+			tag = mapped
+		}
+		if tag == "" || p.ignore[tag] || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	assert.Equal(t, "web-content", normalizeTag(" Web_Content "))
+	assert.Equal(t, "sql-injection", normalizeTag("SQL Injection"))
+	assert.Equal(t, "", normalizeTag(" - "))
+}
+
+func TestFileStem(t *testing.T) {
+	tests := map[string]string{
+		"wordpress.txt":        "wordpress",
+		"raft-large.txt.gz":    "raft-large",
+		"common.fuzz.txt.zst":  "common.fuzz",
+		"README":               "README",
+		".hidden":              ".hidden",
+		"directory-list.XZ":    "directory-list",
+		"names.lst":            "names",
+		"archive.tar.bz2":      "archive",
+		"no-extension.gz":      "no-extension",
+		"version-1.2.3-words":  "version-1.2.3-words",
+		"dirsearch-words.list": "dirsearch-words",
+	}
+	for name, wants := range tests {
+		assert.Equalf(t, wants, fileStem(name), `fileStem(%q)`, name)
+	}
+}
+
+func TestPathTagger(t *testing.T) {
+	tagger, err := newPathTagger("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"discovery", "web-content", "cms", "wordpress"}, tagger.tags("Discovery/Web-Content/CMS/wordpress.txt"))
+	assert.Equal(t, []string{"cms"}, tagger.tags("./CMS/cms.txt"))
+
+	tagger, err = newPathTagger("Web-Content=web, cms=, Api=api", "discovery")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web", "wordpress"}, tagger.tags("Discovery/Web-Content/CMS/wordpress.txt"))
+	assert.Equal(t, []string{"web", "api"}, tagger.tags("Discovery/Web-Content/api/api-endpoints.txt.gz")[:2])
+
+	_, err = newPathTagger("nomapping", "")
+	assert.EqualError(t, err, `invalid tag mapping "nomapping", expected from=to`)
+	_, err = newPathTagger("=to", "")
+	assert.Error(t, err)
+}
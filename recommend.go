@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/proditis/orunmila/store"
+)
+
+// hostTechs lists the technologies detected on a host
+type hostTechs struct {
+	host  string
+	techs []string
+}
+
+// A fingerprint of httpx -json -tech-detect or of the Wappalyzer CLI, the fields of both are decoded at once
+type fingerprint struct {
+	// httpx
+	URL       string   `json:"url"`
+	Input     string   `json:"input"`
+	Tech      []string `json:"tech"`
+	Webserver string   `json:"webserver"`
+	// Wappalyzer
+	URLs         map[string]json.RawMessage `json:"urls"`
+	Technologies []struct {
+		Name string `json:"name"`
+	} `json:"technologies"`
+}
+
+// The host part of a URL, or the URL itself when it has none
+func urlHost(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return rawURL
+}
+
+// Parse a stream of httpx or Wappalyzer JSON fingerprints, merging the technologies of the same host.
+// The versions are dropped, so that PHP:8.1 and PHP 8.1 are both PHP.
+func parseFingerprints(r io.Reader) ([]hostTechs, error) {
+	var hosts []hostTechs
+	index := make(map[string]int)
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var fp fingerprint
+		err := dec.Decode(&fp)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid fingerprint %d: %v", n, err)
+		}
+
+		var host string
+		var techs []string
+		switch {
+		case len(fp.URLs) > 0 || len(fp.Technologies) > 0:
+			urls := make([]string, 0, len(fp.URLs))
+			for u := range fp.URLs {
+				urls = append(urls, u)
+			}
+			sort.Strings(urls)
+			if len(urls) > 0 {
+				host = urlHost(urls[0])
+			}
+			for _, tech := range fp.Technologies {
+				techs = append(techs, tech.Name)
+			}
+		default:
+			host = urlHost(fp.URL)
+			if host == "" {
+				host = fp.Input
+			}
+			for _, tech := range fp.Tech {
+				techs = append(techs, strings.SplitN(tech, ":", 2)[0])
+			}
+			if fp.Webserver != "" {
+				techs = append(techs, strings.SplitN(fp.Webserver, "/", 2)[0])
+			}
+		}
+		if host == "" {
+			return nil, fmt.Errorf("invalid fingerprint %d: no url", n)
+		}
+
+		i, ok := index[host]
+		if !ok {
+			i = len(hosts)
+			index[host] = i
+			hosts = append(hosts, hostTechs{host: host})
+		}
+		for _, tech := range techs {
+			hosts[i].techs = mergeLists(hosts[i].techs, []string{strings.TrimSpace(tech)})
+		}
+	}
+	return hosts, nil
+}
+
+// recommendation is the list of tags chosen for a host, along with the reasons of the choice
+type recommendation struct {
+	host    string
+	tags    []string
+	reasons []string
+}
+
+// Map the technologies of a host to the tags of the database
+func recommendTags(db *store.Store, h hostTechs) (recommendation, error) {
+	rec := recommendation{host: h.host}
+	var candidates []string
+	for _, tech := range h.techs {
+		mappings, err := db.MatchTechTags(tech)
+		if err != nil {
+			return rec, err
+		}
+		if len(mappings) == 0 {
+			rec.reasons = append(rec.reasons, fmt.Sprintf("%s matches no technology pattern", tech))
+			continue
+		}
+		for _, m := range mappings {
+			rec.reasons = append(rec.reasons, fmt.Sprintf("%s matches %q: %s", tech, m.Pattern, strings.Join(m.Tags, ",")))
+			candidates = mergeLists(candidates, m.Tags)
+		}
+	}
+
+	missing, err := db.MissingTags(candidates)
+	if err != nil {
+		return rec, err
+	}
+	skipped := make(map[string]bool)
+	for _, tag := range missing {
+		skipped[tag] = true
+		rec.reasons = append(rec.reasons, fmt.Sprintf("tag %s skipped, it is not in the db", tag))
+	}
+	for _, tag := range candidates {
+		if !skipped[tag] {
+			rec.tags = append(rec.tags, tag)
+		}
+	}
+	return rec, nil
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// The name of the wordlist of a host. The names already used, case insensitively, are numbered
+// so that two hosts sanitized to the same name do not overwrite each other's list.
+func hostFilename(host string, used map[string]bool) string {
+	base := strings.Trim(unsafeFilenameChars.ReplaceAllString(host, "_"), "._")
+	name := base + ".txt"
+	for n := 2; used[strings.ToLower(name)]; n++ {
+		name = fmt.Sprintf("%s-%d.txt", base, n)
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/proditis/orunmila/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// parse args of the recommend subcommand and exec it
+func recommendSubcmd(args []string) error {
+	recommendCmd := flag.NewFlagSet("recommend", flag.ContinueOnError)
+
+	recommendCmd.SetOutput(flag.CommandLine.Output())
+
+	recommendCmd.Usage = func() {
+		fmt.Fprint(recommendCmd.Output(), "Build wordlists out of the technologies detected by httpx or Wappalyzer\n\n")
+		fmt.Fprintln(recommendCmd.Output(), "Usage of orunmila recommend:")
+		fmt.Fprintf(recommendCmd.Output(), "orunmila [-db <db_path>] [-debug] recommend -from FILE [-per-host DIR] [-o FORMAT] [-sort ORDER] [-limit N]\n\n")
+		recommendCmd.PrintDefaults()
+		fmt.Fprintln(recommendCmd.Output(), "\nThe technologies are mapped to tags by the patterns of orunmila techmap, the tags missing from the db are skipped")
+		fmt.Fprintln(recommendCmd.Output(), "\texample: httpx -l hosts.txt -json -tech-detect | orunmila recommend -from - -per-host lists")
+	}
+
+	var (
+		fromPtr    = recommendCmd.String("from", "", "the httpx -json -tech-detect or Wappalyzer JSON output, - reads from the standard input")
+		perHostPtr = recommendCmd.String("per-host", "", "write one wordlist per host in this directory instead of a merged list to the standard output")
+		outputPtr  = recommendCmd.String("o", "plain", "the output format ("+strings.Join(outputFormats, "|")+")")
+		sortPtr    = recommendCmd.String("sort", "", "the order of the words ("+strings.Join(store.SortOrders, "|")+")")
+		limitPtr   = recommendCmd.Int("limit", 0, "the maximum number of words of each list (default: no limit)")
+	)
+
+	err := recommendCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	log.Debugln("[recommendSubcmd] using db:", *dbPtr)
+	log.Debugln("[recommendSubcmd] from:", *fromPtr, "per host:", *perHostPtr)
+	log.Debugln("[recommendSubcmd] output format:", *outputPtr, "sort:", *sortPtr, "limit:", *limitPtr)
+
+	if *fromPtr == "" {
+		recommendCmd.Usage()
+		err = fmt.Errorf("recommend needs -from")
+		log.Errorln("[recommendSubcmd]", err)
+		return err
+	}
+	if err = recommend(*fromPtr, *perHostPtr, *outputPtr, store.SearchOptions{Sort: *sortPtr, Limit: *limitPtr}); err != nil {
+		log.Errorln("[recommendSubcmd]", err)
+	}
+	return err
+}
+
+// Recommend the tags of every host of a fingerprints file and write their words
+func recommend(from, perHost, format string, opts store.SearchOptions) error {
+	// check the format before any list is created
+	if _, err := newResultWriter(format, io.Discard, false); err != nil {
+		return err
+	}
+	file, err := openInput(from)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hosts, err := parseFingerprints(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", from, err)
+	}
+	if perHost != "" {
+		if err = os.MkdirAll(perHost, 0755); err != nil {
+			return err
+		}
+	}
+
+	db := openStoreReadOnly()
+	defer db.Close()
+
+	var merged []string
+	filenames := make(map[string]bool)
+	for _, h := range hosts {
+		rec, err := recommendTags(db, h)
+		if err != nil {
+			return err
+		}
+		for _, reason := range rec.reasons {
+			log.Infof("[recommend] %s: %s", rec.host, reason)
+		}
+		if len(rec.tags) == 0 {
+			log.Warnf("[recommend] %s: no tags chosen", rec.host)
+			continue
+		}
+		log.Infof("[recommend] %s: chose tags %s", rec.host, strings.Join(rec.tags, ","))
+		merged = mergeLists(merged, rec.tags)
+		if perHost == "" {
+			continue
+		}
+
+		filename := filepath.Join(perHost, hostFilename(rec.host, filenames))
+		if err = writeRecommendation(db, filename, format, rec.tags, opts); err != nil {
+			return err
+		}
+		log.Infof("[recommend] %s: wrote %s", rec.host, filename)
+	}
+	if perHost != "" {
+		return nil
+	}
+	if len(merged) == 0 {
+		return fmt.Errorf("no tags chosen for any of the %d hosts", len(hosts))
+	}
+	log.Infof("[recommend] merged tags %s", strings.Join(merged, ","))
+	out, err := newResultWriter(format, os.Stdout, false)
+	if err != nil {
+		return err
+	}
+	opts.Query = store.TagsQuery(merged)
+	return searchWords(db, opts, out)
+}
+
+// Write the words of any of the tags to a file
+func writeRecommendation(db *store.Store, filename, format string, tags []string, opts store.SearchOptions) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	out, err := newResultWriter(format, file, false)
+	if err == nil {
+		opts.Query = store.TagsQuery(tags)
+		err = searchWords(db, opts, out)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
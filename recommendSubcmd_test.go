@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecommendSubcmd(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "php", "index.php"})
+	addSubcmd([]string{"-tags", "drupal", "user/login"})
+	addSubcmd([]string{"-tags", "nodejs", "package.json"})
+
+	dir := t.TempDir()
+	from := filepath.Join(dir, "httpx.jsonl")
+	assert.NoError(t, os.WriteFile(from, []byte(httpxFingerprints), 0644))
+
+	assert.Equal(t, flag.ErrHelp, recommendSubcmd([]string{"-help"}))
+	assert.EqualError(t, recommendSubcmd([]string{}), "recommend needs -from")
+	assert.Error(t, recommendSubcmd([]string{"-from", filepath.Join(dir, "missing.jsonl")}))
+
+	buf.Reset()
+	assert.NoError(t, recommendSubcmd([]string{"-from", from}))
+	assert.Contains(t, buf.String(), `Drupal matches \"drupal\": drupal,php`)
+	assert.Contains(t, buf.String(), "tag nginx skipped, it is not in the db")
+	assert.Contains(t, buf.String(), "shop.example.com:443: chose tags php,drupal")
+	assert.Contains(t, buf.String(), "merged tags php,drupal,nodejs")
+
+	lists := filepath.Join(dir, "lists")
+	assert.NoError(t, recommendSubcmd([]string{"-from", from, "-per-host", lists, "-sort", "alpha"}))
+	data, err := os.ReadFile(filepath.Join(lists, "shop.example.com_443.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "index.php\nuser/login\n", string(data))
+	data, err = os.ReadFile(filepath.Join(lists, "api.example.com.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "package.json\n", string(data))
+
+	// an unknown format is reported before any list is written
+	invalid := filepath.Join(dir, "invalid")
+	assert.EqualError(t, recommendSubcmd([]string{"-from", from, "-per-host", invalid, "-o", "xml"}), `unknown output format "xml", valid formats are plain, json, ndjson, csv, tsv, tagged`)
+	assert.NoDirExists(t, invalid)
+
+	// the hosts sanitized to the same name get a list each
+	collide := filepath.Join(dir, "collide.jsonl")
+	assert.NoError(t, os.WriteFile(collide, []byte(`{"url":"http://a.example.com:8080","tech":["PHP"]}
+{"url":"http://a.example.com_8080","tech":["Node.js"]}
+`), 0644))
+	lists = filepath.Join(dir, "collide")
+	assert.NoError(t, recommendSubcmd([]string{"-from", collide, "-per-host", lists}))
+	data, err = os.ReadFile(filepath.Join(lists, "a.example.com_8080.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "index.php\n", string(data))
+	data, err = os.ReadFile(filepath.Join(lists, "a.example.com_8080-2.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "package.json\n", string(data))
+}
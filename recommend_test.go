@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const httpxFingerprints = `{"timestamp":"2026-10-18T10:00:00Z","url":"https://shop.example.com:443","input":"shop.example.com","status_code":200,"webserver":"nginx/1.18.0","tech":["Nginx:1.18.0","PHP:8.1","Drupal:10"]}
+{"url":"http://api.example.com","input":"api.example.com","tech":["Express","Node.js"]}
+{"url":"https://shop.example.com:443/admin","tech":["jQuery"]}
+`
+
+const wappalyzerFingerprint = `{"urls":{"https://intranet.example.com/":{"status":200}},"technologies":[{"slug":"microsoft-iis","name":"IIS","confidence":100,"version":"10.0"},{"slug":"asp-net","name":"Microsoft ASP.NET","version":null}]}`
+
+func TestParseFingerprints(t *testing.T) {
+	hosts, err := parseFingerprints(strings.NewReader(httpxFingerprints + wappalyzerFingerprint))
+	assert.NoError(t, err)
+	assert.Equal(t, []hostTechs{
+		{host: "shop.example.com:443", techs: []string{"Nginx", "PHP", "Drupal", "nginx", "jQuery"}},
+		{host: "api.example.com", techs: []string{"Express", "Node.js"}},
+		{host: "intranet.example.com", techs: []string{"IIS", "Microsoft ASP.NET"}},
+	}, hosts)
+
+	_, err = parseFingerprints(strings.NewReader(`{"url":"https://x"} not json`))
+	assert.EqualError(t, err, "invalid fingerprint 2: invalid character 'o' in literal null (expecting 'u')")
+	_, err = parseFingerprints(strings.NewReader(`{"tech":["PHP"]}`))
+	assert.EqualError(t, err, "invalid fingerprint 1: no url")
+}
+
+func TestHostFilename(t *testing.T) {
+	used := make(map[string]bool)
+	assert.Equal(t, "shop.example.com_443.txt", hostFilename("shop.example.com:443", used))
+	assert.Equal(t, "a_b.txt", hostFilename("../a/b", used))
+	// the hosts sanitized to a name already used are numbered
+	assert.Equal(t, "shop.example.com_443-2.txt", hostFilename("shop.example.com/443", used))
+	assert.Equal(t, "A_B-2.txt", hostFilename("A:B", used))
+	assert.Equal(t, "a_b-3.txt", hostFilename("a?b", used))
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// rule is a line of a rules file, its functions are applied in order to every word
+type rule struct {
+	funcs []func([]rune) []rune
+}
+
+// Apply the functions of the rule to a word
+func (r rule) apply(word string) string {
+	runes := []rune(word)
+	for _, fn := range r.funcs {
+		runes = fn(runes)
+	}
+	return string(runes)
+}
+
+// Decode a hashcat position, 0-9 then A-Z for 10 to 35
+func rulePosition(c rune) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10, true
+	}
+	return 0, false
+}
+
+// Map every rune of a word
+func mapRunes(word []rune, fn func(rune) rune) []rune {
+	out := make([]rune, len(word))
+	for i, r := range word {
+		out[i] = fn(r)
+	}
+	return out
+}
+
+// Invert the case of a rune
+func toggleCase(r rune) rune {
+	if unicode.IsUpper(r) {
+		return unicode.ToLower(r)
+	}
+	return unicode.ToUpper(r)
+}
+
+// Reverse a word
+func reverseRunes(word []rune) []rune {
+	out := make([]rune, len(word))
+	for i, r := range word {
+		out[len(word)-1-i] = r
+	}
+	return out
+}
+
+// The rule functions without arguments, following the hashcat rule syntax
+var ruleFuncs = map[rune]func([]rune) []rune{
+	':': func(w []rune) []rune { return w },
+	'l': func(w []rune) []rune { return mapRunes(w, unicode.ToLower) },
+	'u': func(w []rune) []rune { return mapRunes(w, unicode.ToUpper) },
+	'c': func(w []rune) []rune {
+		w = mapRunes(w, unicode.ToLower)
+		if len(w) > 0 {
+			w[0] = unicode.ToUpper(w[0])
+		}
+		return w
+	},
+	'C': func(w []rune) []rune {
+		w = mapRunes(w, unicode.ToUpper)
+		if len(w) > 0 {
+			w[0] = unicode.ToLower(w[0])
+		}
+		return w
+	},
+	't': func(w []rune) []rune { return mapRunes(w, toggleCase) },
+	'r': reverseRunes,
+	'd': func(w []rune) []rune { return append(append([]rune{}, w...), w...) },
+	'f': func(w []rune) []rune { return append(append([]rune{}, w...), reverseRunes(w)...) },
+	'{': func(w []rune) []rune {
+		if len(w) == 0 {
+			return w
+		}
+		return append(append([]rune{}, w[1:]...), w[0])
+	},
+	'}': func(w []rune) []rune {
+		if len(w) == 0 {
+			return w
+		}
+		return append([]rune{w[len(w)-1]}, w[:len(w)-1]...)
+	},
+	'[': func(w []rune) []rune {
+		if len(w) == 0 {
+			return w
+		}
+		return w[1:]
+	},
+	']': func(w []rune) []rune {
+		if len(w) == 0 {
+			return w
+		}
+		return w[:len(w)-1]
+	},
+}
+
+// The number of arguments of the rule functions that take some
+var ruleArgs = map[rune]int{'$': 1, '^': 1, '@': 1, 's': 2, 'T': 1, 'p': 1, 'D': 1, '\'': 1}
+
+// Parse a line of a rules file. Supported functions are : l u c C t TN r d pN f { } [ ] DN 'N $X ^X sXY and @X,
+// where N is a position (0-9, A-Z) and X, Y are characters. Spaces between functions are ignored.
+func parseRule(text string) (rule, error) {
+	var r rule
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if c == ' ' || c == '\t' {
+			continue
+		}
+		if fn, ok := ruleFuncs[c]; ok {
+			r.funcs = append(r.funcs, fn)
+			continue
+		}
+		args, ok := ruleArgs[c]
+		if !ok {
+			return r, fmt.Errorf("unsupported rule function %q at position %d", c, i+1)
+		}
+		if i+args >= len(runes) {
+			return r, fmt.Errorf("missing argument of %q at position %d", c, i+1)
+		}
+		arg, arg2 := runes[i+1], runes[i+args]
+		i += args
+
+		switch c {
+		case '$':
+			r.funcs = append(r.funcs, func(w []rune) []rune { return append(append([]rune{}, w...), arg) })
+			continue
+		case '^':
+			r.funcs = append(r.funcs, func(w []rune) []rune { return append([]rune{arg}, w...) })
+			continue
+		case 's':
+			r.funcs = append(r.funcs, func(w []rune) []rune {
+				return mapRunes(w, func(x rune) rune {
+					if x == arg {
+						return arg2
+					}
+					return x
+				})
+			})
+			continue
+		case '@':
+			r.funcs = append(r.funcs, func(w []rune) []rune {
+				out := make([]rune, 0, len(w))
+				for _, x := range w {
+					if x != arg {
+						out = append(out, x)
+					}
+				}
+				return out
+			})
+			continue
+		}
+
+		// the remaining functions take a position
+		n, ok := rulePosition(arg)
+		if !ok {
+			return r, fmt.Errorf("invalid position %q of %q at position %d", arg, c, i+1)
+		}
+		switch c {
+		case 'T':
+			r.funcs = append(r.funcs, func(w []rune) []rune {
+				if n >= len(w) {
+					return w
+				}
+				w = append([]rune{}, w...)
+				w[n] = toggleCase(w[n])
+				return w
+			})
+		case 'p':
+			r.funcs = append(r.funcs, func(w []rune) []rune {
+				out := make([]rune, 0, len(w)*(n+1))
+				for j := 0; j <= n; j++ {
+					out = append(out, w...)
+				}
+				return out
+			})
+		case 'D':
+			r.funcs = append(r.funcs, func(w []rune) []rune {
+				if n >= len(w) {
+					return w
+				}
+				return append(append([]rune{}, w[:n]...), w[n+1:]...)
+			})
+		case '\'':
+			r.funcs = append(r.funcs, func(w []rune) []rune {
+				if n >= len(w) {
+					return w
+				}
+				return w[:n]
+			})
+		}
+	}
+	if len(r.funcs) == 0 {
+		return r, fmt.Errorf("empty rule")
+	}
+	return r, nil
+}
+
+// Load the rules of a file, or the standard input for "-", one per line.
+// Blank lines and lines starting with # are skipped.
+func loadRules(filename string) ([]rule, error) {
+	file, err := openInput(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []rule
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		r, err := parseRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid rule %q: %v", filename, line, text, err)
+		}
+		rules = append(rules, r)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("%s: no rules found", filename)
+	}
+	return rules, nil
+}
+
+// ruleExpansion applies the rules to a word, the candidates of the rules in order, empty ones left out
+func ruleExpansion(rules []rule) func(string) []string {
+	return func(word string) []string {
+		candidates := make([]string, 0, len(rules))
+		for _, rl := range rules {
+			if candidate := rl.apply(word); candidate != "" {
+				candidates = append(candidates, candidate)
+			}
+		}
+		return candidates
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/proditis/orunmila/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		rule, word, want string
+	}{
+		{":", "Admin", "Admin"},
+		{"l", "AdMin", "admin"},
+		{"u", "admin", "ADMIN"},
+		{"c", "aDMIN", "Admin"},
+		{"C", "admin", "aDMIN"},
+		{"t", "AdMin", "aDmIN"},
+		{"T0 T2", "admin", "AdMin"},
+		{"T9", "admin", "admin"},
+		{"r", "admin", "nimda"},
+		{"d", "ab", "abab"},
+		{"p2", "ab", "ababab"},
+		{"f", "ab", "abba"},
+		{"{", "admin", "dmina"},
+		{"}", "admin", "nadmi"},
+		{"[", "admin", "dmin"},
+		{"]", "admin", "admi"},
+		{"D1", "admin", "amin"},
+		{"'3", "admin", "adm"},
+		{"$1 $2 $3", "admin", "admin123"},
+		{"^_", "admin", "_admin"},
+		{"sa4 si1", "admin", "4dm1n"},
+		{"@a", "banana", "bnn"},
+		{"c$2$0$2$6", "admin", "Admin2026"},
+		{"$é", "caf", "café"},
+		{"]", "", ""},
+	}
+	for _, test := range tests {
+		r, err := parseRule(test.rule)
+		if assert.NoErrorf(t, err, `rule %q`, test.rule) {
+			assert.Equalf(t, test.want, r.apply(test.word), `rule %q on %q`, test.rule, test.word)
+		}
+	}
+
+	errors := map[string]string{
+		"":    "empty rule",
+		"  ":  "empty rule",
+		"z":   `unsupported rule function 'z' at position 1`,
+		"u $": `missing argument of '$' at position 3`,
+		"sa":  `missing argument of 's' at position 1`,
+		"T!":  `invalid position '!' of 'T' at position 2`,
+	}
+	for text, want := range errors {
+		_, err := parseRule(text)
+		assert.EqualErrorf(t, err, want, `rule %q`, text)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "test.rule")
+	assert.NoError(t, os.WriteFile(filename, []byte("# comment\n:\n\nc\r\n$1\n"), 0644))
+	rules, err := loadRules(filename)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 3)
+
+	assert.NoError(t, os.WriteFile(filename, []byte(":\nu\nz\n"), 0644))
+	_, err = loadRules(filename)
+	assert.EqualError(t, err, filename+`:3: invalid rule "z": unsupported rule function 'z' at position 1`)
+
+	assert.NoError(t, os.WriteFile(filename, []byte("# nothing\n"), 0644))
+	_, err = loadRules(filename)
+	assert.EqualError(t, err, filename+`: no rules found`)
+
+	_, err = loadRules(filepath.Join(dir, "missing.rule"))
+	assert.Error(t, err)
+}
+
+func TestRuleExpansion(t *testing.T) {
+	var rules []rule
+	for _, text := range []string{":", "l", "c", "$1"} {
+		r, err := parseRule(text)
+		assert.NoError(t, err)
+		rules = append(rules, r)
+	}
+
+	var out bytes.Buffer
+	next, _ := newResultWriter("plain", &out, true)
+	w := newExpandWriter(next, ruleExpansion(rules))
+	assert.NoError(t, w.Write(store.Record{Word: "Admin", Tags: []string{"a"}, ID: 1}))
+	assert.NoError(t, w.Write(store.Record{Word: "admin", Tags: []string{"b"}, ID: 2}))
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "Admin a\nadmin a\nAdmin1 a\nadmin1 b\n", out.String())
+	assert.Equal(t, int64(4), w.candidates)
+	assert.Equal(t, int64(4), w.duplicates)
+}
+
+func TestSearchSubcmdRules(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "a", "admin"})
+
+	filename := filepath.Join(t.TempDir(), "test.rule")
+	assert.NoError(t, os.WriteFile(filename, []byte("u\n"), 0644))
+	assert.NoError(t, searchSubcmd([]string{"-tags", "a", "-rules", filename}))
+	assert.Contains(t, buf.String(), "1 words x 1 rules, an estimated 1 candidates at most")
+	assert.Error(t, searchSubcmd([]string{"-tags", "a", "-rules", filename + ".missing"}))
+}
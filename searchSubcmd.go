@@ -1,10 +1,13 @@
 package main
 
 import (
-	"database/sql"
 	"flag"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/proditis/orunmila/store"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -18,13 +21,43 @@ func searchSubcmd(args []string) error {
 	searchCmd.Usage = func() {
 		fmt.Fprint(searchCmd.Output(), "Display words matching an optional list of tags\n\n")
 		fmt.Fprintf(searchCmd.Output(), "Usage of orunmila search:\n")
-		fmt.Fprintf(searchCmd.Output(), "orunmila [-db <db_path>] [-debug] search [-st] [-tags OPTIONAL_TAGS]\n\n")
+		fmt.Fprintf(searchCmd.Output(), "orunmila [-db <db_path>] [-debug] search [-st] [-o FORMAT] [-tags OPTIONAL_TAGS] [-q OPTIONAL_QUERY] [-source OPTIONAL_SOURCES] [-since TIME] [-until TIME] [-checkpoint NAME] [-rules FILE] [-ext EXTENSIONS] [-no-tag-ext] [-backup-variants] [-backup-patterns FILE] [-sort ORDER] [-limit N]\n\n")
 		searchCmd.PrintDefaults()
+		fmt.Fprintln(searchCmd.Output(), "\nQueries combine tags with and, or, not and parentheses, -tags is a shorthand for or")
+		fmt.Fprintln(searchCmd.Output(), "\texample: orunmila search -q '(drupal or wordpress) and php and not windows'")
+		fmt.Fprintln(searchCmd.Output(), "\nThe score of a word is the score set with orunmila word score plus the number of its sources, plus the scores of its associations with the tags of the query")
+		fmt.Fprintln(searchCmd.Output(), "\texample: orunmila search -tags php -sort score -limit 5000")
+		fmt.Fprintln(searchCmd.Output(), "\nWith a query, -since and -until apply to the time the words were tagged with the tags the query includes, not the negated ones")
+		fmt.Fprintln(searchCmd.Output(), "\texample: orunmila search -tags api -since 7d")
+		fmt.Fprintln(searchCmd.Output(), "\nA checkpoint only returns the words it did not return before, see orunmila checkpoint")
+		fmt.Fprintln(searchCmd.Output(), "\texample: orunmila search -tags x -checkpoint programXYZ")
+		fmt.Fprintln(searchCmd.Output(), "\nRules files hold one hashcat style rule per line, the supported functions are : l u c C t TN r d pN f { } [ ] DN 'N $X ^X sXY @X")
+		fmt.Fprintln(searchCmd.Output(), "\texample: orunmila search -tags api -rules case-and-years.rule")
+		fmt.Fprintln(searchCmd.Output(), "The number of candidates logged before the output is an estimate, the words times the rules, before the duplicates are dropped")
+		fmt.Fprintf(searchCmd.Output(), "\nExtensions replace the %s placeholders and are appended to the words without one, @name adds the extensions of a set\n", extPlaceholder)
+		fmt.Fprintln(searchCmd.Output(), "The sets linked to the tags of the query are added too, see orunmila ext")
+		fmt.Fprintln(searchCmd.Output(), "\texample: orunmila search -tags php -ext bak,old,@backup")
+		fmt.Fprintln(searchCmd.Output(), "\nBackup variants follow every file like word with its editor and backup leftovers, such as config.php~ or config.old.php")
+		fmt.Fprintln(searchCmd.Output(), "Patterns files add one pattern per line to the built-in ones, using the {file}, {name}, {ext} and {hidden} placeholders")
+		fmt.Fprintln(searchCmd.Output(), "\texample: orunmila search -tags php -ext php -backup-variants")
 	}
 
 	var (
-		tagsPtr     = searchCmd.String("tags", "", "a comma separated list of the tags to use")
-		showTagsPtr = searchCmd.Bool("st", false, "show result tags")
+		tagsPtr       = searchCmd.String("tags", "", "a comma separated list of the tags to use")
+		queryPtr      = searchCmd.String("q", "", "a boolean tag query (and, or, not, parentheses)")
+		showTagsPtr   = searchCmd.Bool("st", false, "show result tags")
+		outputPtr     = searchCmd.String("o", "plain", "the output format ("+strings.Join(outputFormats, "|")+")")
+		sincePtr      = searchCmd.String("since", "", "only the words added since a date (2026-10-01) or a duration ago (7d, 2w, 12h)")
+		untilPtr      = searchCmd.String("until", "", "only the words added before a date (2026-10-01) or a duration ago (7d, 2w, 12h)")
+		rulesPtr      = searchCmd.String("rules", "", "a file of hashcat style rules applied to every word, the duplicate candidates are dropped")
+		checkpointPtr = searchCmd.String("checkpoint", "", "only the words not returned before under this checkpoint name, the returned words are recorded")
+		sortPtr       = searchCmd.String("sort", "", "the order of the words ("+strings.Join(store.SortOrders, "|")+"), score is the highest first (default: insertion order)")
+		limitPtr      = searchCmd.Int("limit", 0, "the maximum number of words returned, before any rules, extensions or variants (default: no limit)")
+		sourcePtr     = searchCmd.String("source", "", "a comma separated list of the sources the words were imported from, as paths, glob patterns or sha256 sums")
+		extPtr        = searchCmd.String("ext", "", "a comma separated list of extensions replacing %EXT% and appended to the words without one, @name for the extensions of a set")
+		noTagExtPtr   = searchCmd.Bool("no-tag-ext", false, "do not add the extensions of the sets linked to the tags of the query")
+		backupPtr     = searchCmd.Bool("backup-variants", false, "follow every file like word with its backup and temporary file variants")
+		patternsPtr   = searchCmd.String("backup-patterns", "", "a file of backup patterns added to the built-in ones, implies -backup-variants")
 	)
 
 	err := searchCmd.Parse(args)
@@ -32,18 +65,159 @@ func searchSubcmd(args []string) error {
 	if err != nil {
 		return err
 	}
-	dsn := fmt.Sprintf("file:%s?mode=ro", *dbPtr)
 	log.Debugln("[searchSubcmd] using db:", *dbPtr)
 	log.Debugln("[searchSubcmd] using tags:", *tagsPtr)
-	log.Debugln("[searchSubcmd] using dsn:", dsn)
+	log.Debugln("[searchSubcmd] using query:", *queryPtr)
 	log.Debugln("[searchSubcmd] show tags:", *showTagsPtr)
+	log.Debugln("[searchSubcmd] output format:", *outputPtr)
+	log.Debugln("[searchSubcmd] using sources:", *sourcePtr)
+	log.Debugln("[searchSubcmd] since:", *sincePtr, "until:", *untilPtr)
+	log.Debugln("[searchSubcmd] checkpoint:", *checkpointPtr)
+	log.Debugln("[searchSubcmd] sort:", *sortPtr, "limit:", *limitPtr)
+	log.Debugln("[searchSubcmd] rules:", *rulesPtr)
+	log.Debugln("[searchSubcmd] extensions:", *extPtr, "no tag extensions:", *noTagExtPtr)
+	log.Debugln("[searchSubcmd] backup variants:", *backupPtr, "backup patterns:", *patternsPtr)
 
-	Tags = stringToArray(*tagsPtr)
+	out, err := newResultWriter(*outputPtr, os.Stdout, *showTagsPtr)
+	if err != nil {
+		log.Errorln("[searchSubcmd]", err)
+		return err
+	}
+
+	expr, err := buildQuery(*tagsPtr, *queryPtr)
+	if err != nil {
+		log.Errorln("[searchSubcmd] invalid query:", err)
+		return err
+	}
 
-	db, err := sql.Open("sqlite3", dsn)
-	check(err)
+	var rules []rule
+	if *rulesPtr != "" {
+		if rules, err = loadRules(*rulesPtr); err != nil {
+			log.Errorln("[searchSubcmd]", err)
+			return err
+		}
+	}
+
+	var patterns []backupPattern
+	if *backupPtr || *patternsPtr != "" {
+		if patterns, err = loadBackupPatterns(*patternsPtr); err != nil {
+			log.Errorln("[searchSubcmd]", err)
+			return err
+		}
+	}
+
+	opts := store.SearchOptions{Query: expr, Sources: splitList(*sourcePtr), Checkpoint: strings.TrimSpace(*checkpointPtr), Sort: *sortPtr, Limit: *limitPtr}
+	now := time.Now()
+	if opts.Since, err = parseTime(*sincePtr, now); err == nil {
+		opts.Until, err = parseTime(*untilPtr, now)
+	}
+	if err != nil {
+		log.Errorln("[searchSubcmd]", err)
+		return err
+	}
+
+	// recording the words of a checkpoint needs to write to the database
+	var db *store.Store
+	if opts.Checkpoint != "" {
+		db = openStore()
+	} else {
+		db = openStoreReadOnly()
+	}
 	defer db.Close()
 
-	searchWordsByTagIds(db, *tagsPtr, *showTagsPtr)
-	return nil
+	exts, err := resolveExtensions(db, splitList(*extPtr), expr, !*noTagExtPtr)
+	if err != nil {
+		log.Errorln("[searchSubcmd]", err)
+		return err
+	}
+	if len(exts) > 0 {
+		log.Infoln("[searchSubcmd] using extensions:", strings.Join(exts, ","))
+	}
+
+	// the rules apply to the words, the extensions to their candidates and the backup variants last
+	var expansions []func(string) []string
+	if len(rules) > 0 {
+		expansions = append(expansions, ruleExpansion(rules))
+	}
+	if len(exts) > 0 {
+		expansions = append(expansions, extExpansion(exts))
+	}
+	if len(patterns) > 0 {
+		expansions = append(expansions, backupExpansion(patterns))
+	}
+	if len(expansions) > 0 {
+		out = newExpandWriter(out, expansions...)
+	}
+	if len(rules) > 0 {
+		words, err := db.Count(opts)
+		if err != nil {
+			log.Errorln("[searchSubcmd]", err)
+			return err
+		}
+		log.Infof("[searchSubcmd] %d words x %d rules, an estimated %d candidates at most", words, len(rules), words*int64(len(rules)))
+	}
+
+	err = searchWords(db, opts, out)
+	if err != nil {
+		log.Errorln("[searchSubcmd]", err)
+	}
+	return err
+}
+
+// Build the query of a comma separated list of tags and a boolean tag query, both optional.
+// The tags are or'ed together and and'ed with the query, nil is returned when both are empty.
+func buildQuery(tags, query string) (store.Expr, error) {
+	expr := store.TagsQuery(splitList(tags))
+	if strings.TrimSpace(query) == "" {
+		return expr, nil
+	}
+	queryExpr, err := store.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if expr == nil {
+		return queryExpr, nil
+	}
+	return store.And(expr, queryExpr), nil
+}
+
+// Search for words matching the options and write them to out, a nil query returns every word
+func searchWords(db *store.Store, opts store.SearchOptions, out resultWriter) error {
+	if opts.Query != nil {
+		log.Infoln("Using tag query:", opts.Query)
+		missing, err := db.MissingTags(store.TagNames(opts.Query))
+		if err != nil {
+			return err
+		}
+		for _, tag := range missing {
+			log.Warnf("tag %q not found in the db\n", tag)
+		}
+	} else {
+		log.Infoln("No tags were given")
+	}
+
+	if len(opts.Sources) > 0 {
+		log.Infoln("Using sources:", opts.Sources)
+	}
+	if !opts.Since.IsZero() {
+		log.Infoln("Since:", opts.Since.Format(time.RFC3339))
+	}
+	if !opts.Until.IsZero() {
+		log.Infoln("Until:", opts.Until.Format(time.RFC3339))
+	}
+	if opts.Sort != "" {
+		log.Infoln("Sorting by:", opts.Sort)
+	}
+	if opts.Limit > 0 {
+		log.Infoln("Limit:", opts.Limit)
+	}
+	if opts.Checkpoint != "" {
+		log.Infoln("Skipping the words already returned under checkpoint:", opts.Checkpoint)
+	}
+
+	err := db.Search(opts, out.Write)
+	if err != nil {
+		return err
+	}
+	return out.Close()
 }
@@ -8,6 +8,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/proditis/orunmila/store"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
@@ -48,7 +49,28 @@ func TestSearchSubcmdTags(t *testing.T) {
 
 	args := []string{"-tags", "a,b,c"}
 	err := searchSubcmd(args)
-	assert.NoError(t, err, `Failed to perform search with tags %v`, args)
+	assert.EqualError(t, err, `none of the tags in "((a or b) or c)" exist in the db`)
+}
+
+// test boolean queries
+func TestSearchSubcmdQuery(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+
+	addSubcmd([]string{"-tags", "php,drupal", "drupal-php"})
+	addSubcmd([]string{"-tags", "php,windows", "windows-php"})
+
+	args := []string{"-q", "(drupal or wordpress) and php and not windows"}
+	err := searchSubcmd(args)
+	assert.NoError(t, err, `Failed to perform search with query %v`, args)
+
+	args = []string{"-q", "(drupal or"}
+	err = searchSubcmd(args)
+	assert.EqualError(t, err, `unexpected end of query`)
+
+	args = []string{"-q", "missing and absent"}
+	err = searchSubcmd(args)
+	assert.EqualError(t, err, `none of the tags in "(missing and absent)" exist in the db`)
 }
 
 func TestSearchSubcmdEmptyTags(t *testing.T) {
@@ -95,3 +117,25 @@ func TestSearchSubcmdExistingTags(t *testing.T) {
 	assert.NoError(t, err, `Failed to select count(*) from wt`)
 	assert.Equal(t, int64(2*3), Nrecords, `Number of words records returned did not match`)
 }
+
+func TestSearchWordsByTagIds(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	db := openStore()
+	defer db.Close()
+
+	var out bytes.Buffer
+	w, _ := newResultWriter("plain", &out, true)
+	err := searchWords(db, store.SearchOptions{Query: store.TagsQuery([]string{"a"})}, w)
+	assert.EqualError(t, err, `none of the tags in "a" exist in the db`)
+
+	assert.NoError(t, db.AddWords([]string{"one"}, []string{"a"}))
+	assert.NoError(t, db.AddWords([]string{"two words"}, []string{"a", "b"}))
+
+	out.Reset()
+	w, _ = newResultWriter("ndjson", &out, false)
+	err = searchWords(db, store.SearchOptions{Query: store.TagsQuery([]string{"b", "missing"})}, w)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"word\":\"two words\",\"tags\":[\"a\",\"b\"],\"id\":2}\n", out.String())
+	assert.Contains(t, buf.String(), `tag \"missing\" not found in the db`)
+}
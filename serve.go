@@ -0,0 +1,522 @@
+package main
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/proditis/orunmila/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// access is the level of access granted by an API token
+type access int
+
+const (
+	accessNone access = iota
+	// accessRead allows searching and listing
+	accessRead
+	// accessWrite allows adding and importing words, and recording checkpoints, on top of accessRead
+	accessWrite
+)
+
+func (a access) String() string {
+	switch a {
+	case accessRead:
+		return "ro"
+	case accessWrite:
+		return "rw"
+	}
+	return "none"
+}
+
+// apiToken is a bearer token along with the access it grants
+type apiToken struct {
+	value  string
+	access access
+}
+
+// Load the API tokens of a file, one access:token pair per line where access is ro or rw.
+// Blank lines and lines starting with # are skipped.
+func loadTokens(filename string) ([]apiToken, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var tokens []apiToken
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		level, value, found := strings.Cut(line, ":")
+		value = strings.TrimSpace(value)
+		if !found || value == "" {
+			return nil, fmt.Errorf("%s:%d: expected ro:TOKEN or rw:TOKEN", filename, n)
+		}
+		token := apiToken{value: value}
+		switch strings.TrimSpace(level) {
+		case "ro":
+			token.access = accessRead
+		case "rw":
+			token.access = accessWrite
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown access %q, expected ro or rw", filename, n, level)
+		}
+		tokens = append(tokens, token)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%s: no tokens found", filename)
+	}
+	return tokens, nil
+}
+
+// The default maximum size of the bodies of add and import requests
+const defaultMaxUpload = 1 << 30
+
+// writeHeader must be set on the write requests of a server without tokens. A browser does not send it
+// across sites without the consent of the server, so that the pages the operator visits cannot write.
+const writeHeader = "X-Orunmila-Write"
+
+// server exposes a database over HTTP
+type server struct {
+	db *store.Store
+	// tokens are the accepted bearer tokens, anyone has read access when empty and write access with writeHeader
+	tokens []apiToken
+	// maxUpload is the maximum size in bytes of the request bodies
+	maxUpload int64
+	// ui serves the web UI under / along with the API
+	ui bool
+}
+
+// Create the handler of the API routes
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/search", s.handle(http.MethodGet, accessRead, s.search))
+	mux.HandleFunc("/api/count", s.handle(http.MethodGet, accessRead, s.count))
+	mux.HandleFunc("/api/tags", s.handle(http.MethodGet, accessRead, s.tags))
+	mux.HandleFunc("/api/overlap", s.handle(http.MethodGet, accessRead, s.overlap))
+	mux.HandleFunc("/api/info", s.handle(http.MethodGet, accessRead, s.info))
+	mux.HandleFunc("/api/words", s.handle(http.MethodPost, accessWrite, s.addWords))
+	mux.HandleFunc("/api/import", s.handle(http.MethodPost, accessWrite, s.importWords))
+	if s.ui {
+		mux.Handle("/", webHandler())
+	}
+	return mux
+}
+
+// The access granted to a request by its Authorization header, or by writeHeader without tokens
+func (s *server) access(r *http.Request) access {
+	if len(s.tokens) == 0 {
+		if r.Header.Get(writeHeader) == "" {
+			return accessRead
+		}
+		return accessWrite
+	}
+	scheme, value, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return accessNone
+	}
+	value = strings.TrimSpace(value)
+	granted := accessNone
+	for _, token := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(token.value), []byte(value)) == 1 && token.access > granted {
+			granted = token.access
+		}
+	}
+	return granted
+}
+
+// An API handler, it is given the access of the request and returns the status and error of failed requests
+type apiHandler func(w http.ResponseWriter, r *http.Request, granted access) (int, error)
+
+// Wrap an API handler with the checks of the request method and of the access it needs, and the reporting of its errors
+func (s *server) handle(method string, needs access, h apiHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		status, err := s.serve(w, r, method, needs, h)
+		if err != nil {
+			log.Warnf("[serve] %s %s %s: %d %v", r.RemoteAddr, r.Method, r.URL.Path, status, err)
+			if status != 0 {
+				writeJSON(w, status, map[string]string{"error": err.Error()})
+			}
+			return
+		}
+		log.Infof("[serve] %s %s %s %s", r.RemoteAddr, r.Method, r.URL.RequestURI(), time.Since(start).Round(time.Millisecond))
+	}
+}
+
+func (s *server) serve(w http.ResponseWriter, r *http.Request, method string, needs access, h apiHandler) (int, error) {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		return http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method)
+	}
+	granted := s.access(r)
+	if granted == accessNone {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="orunmila"`)
+		return http.StatusUnauthorized, errors.New("missing or invalid bearer token")
+	}
+	if granted < needs {
+		return http.StatusForbidden, s.accessError("this request", needs)
+	}
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxUpload)
+	}
+	return h(w, r, granted)
+}
+
+// The error of a request lacking the access it needs
+func (s *server) accessError(what string, needs access) error {
+	if len(s.tokens) == 0 {
+		return fmt.Errorf("%s needs the %s header", what, writeHeader)
+	}
+	return fmt.Errorf("%s needs a %s token", what, needs)
+}
+
+// Write a value as the JSON body of a response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warnln("[serve]", err)
+	}
+}
+
+// The content types of the output formats
+var contentTypes = map[string]string{
+	"plain":  "text/plain; charset=utf-8",
+	"json":   "application/json",
+	"ndjson": "application/x-ndjson",
+	"csv":    "text/csv; charset=utf-8",
+	"tsv":    "text/tab-separated-values; charset=utf-8",
+	"tagged": "text/plain; charset=utf-8",
+}
+
+// startedWriter records whether a response was started, after which its status can no longer change
+type startedWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *startedWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Check if a boolean query parameter is set, an empty value counts as true
+func boolParam(r *http.Request, name string) (bool, error) {
+	values, ok := r.URL.Query()[name]
+	if !ok {
+		return false, nil
+	}
+	if values[0] == "" {
+		return true, nil
+	}
+	b, err := strconv.ParseBool(values[0])
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q", name, values[0])
+	}
+	return b, nil
+}
+
+// Parse the search options of a request, out of the parameters of the search subcommand
+func searchOptions(params url.Values) (store.SearchOptions, error) {
+	expr, err := buildQuery(params.Get("tags"), params.Get("q"))
+	if err != nil {
+		return store.SearchOptions{}, fmt.Errorf("invalid query: %w", err)
+	}
+	opts := store.SearchOptions{Query: expr, Sources: splitList(params.Get("source")), Checkpoint: strings.TrimSpace(params.Get("checkpoint")), Sort: params.Get("sort")}
+	if limit := params.Get("limit"); limit != "" {
+		if opts.Limit, err = strconv.Atoi(limit); err != nil || opts.Limit < 0 {
+			return opts, fmt.Errorf("invalid limit %q", limit)
+		}
+	}
+	now := time.Now()
+	if opts.Since, err = parseTime(params.Get("since"), now); err == nil {
+		opts.Until, err = parseTime(params.Get("until"), now)
+	}
+	return opts, err
+}
+
+// GET /api/search streams the words matching the parameters of the search subcommand
+func (s *server) search(w http.ResponseWriter, r *http.Request, granted access) (int, error) {
+	params := r.URL.Query()
+	opts, err := searchOptions(params)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if opts.Checkpoint != "" && granted < accessWrite {
+		return http.StatusForbidden, s.accessError("a checkpoint", accessWrite)
+	}
+	showTags, err := boolParam(r, "st")
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	format := strings.ToLower(params.Get("o"))
+	if format == "" {
+		format = "plain"
+	}
+	sw := &startedWriter{ResponseWriter: w}
+	out, err := newResultWriter(format, sw, showTags)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	out, status, err := s.expandWriter(r, opts.Query, out)
+	if err != nil {
+		return status, err
+	}
+
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if err = searchWords(s.db, opts, out); err != nil {
+		if sw.started {
+			// too late to report the error, the client sees a truncated response
+			return 0, err
+		}
+		w.Header().Del("Content-Type")
+		return http.StatusBadRequest, err
+	}
+	return http.StatusOK, nil
+}
+
+// Wrap out with the backup variants and the extensions asked for by the parameters of a search,
+// returning the status of any error. out is returned as is when none are asked for.
+func (s *server) expandWriter(r *http.Request, query store.Expr, out resultWriter) (resultWriter, int, error) {
+	backup, err := boolParam(r, "backup-variants")
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	noTagExt, err := boolParam(r, "no-tag-ext")
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	var expansions []func(string) []string
+	exts, err := resolveExtensions(s.db, splitList(r.URL.Query().Get("ext")), query, !noTagExt)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if len(exts) > 0 {
+		expansions = append(expansions, extExpansion(exts))
+	}
+	if backup {
+		patterns, err := loadBackupPatterns("")
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		expansions = append(expansions, backupExpansion(patterns))
+	}
+	if len(expansions) == 0 {
+		return out, http.StatusOK, nil
+	}
+	return newExpandWriter(out, expansions...), http.StatusOK, nil
+}
+
+// countWriter counts the records that reach it
+type countWriter struct {
+	n int64
+}
+
+func (c *countWriter) Write(store.Record) error {
+	c.n++
+	return nil
+}
+
+func (c *countWriter) Close() error {
+	return nil
+}
+
+// GET /api/tags lists the tags along with their number of words
+func (s *server) tags(w http.ResponseWriter, r *http.Request, granted access) (int, error) {
+	tags, err := s.db.Tags()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if tags == nil {
+		tags = []store.TagInfo{}
+	}
+	writeJSON(w, http.StatusOK, tags)
+	return http.StatusOK, nil
+}
+
+// GET /api/count counts the words matching the parameters of the search subcommand, along with the
+// extensions and backup variants the search adds, a checkpoint is not recorded
+func (s *server) count(w http.ResponseWriter, r *http.Request, granted access) (int, error) {
+	opts, err := searchOptions(r.URL.Query())
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	counter := &countWriter{}
+	out, status, err := s.expandWriter(r, opts.Query, counter)
+	if err != nil {
+		return status, err
+	}
+	var count int64
+	if out == resultWriter(counter) {
+		count, err = s.db.Count(opts)
+	} else {
+		// the candidates are counted as the search writes them, so that the count matches the list
+		if err = s.db.Peek(opts, out.Write); err == nil {
+			err = out.Close()
+		}
+		count = counter.n
+	}
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{"words": count})
+	return http.StatusOK, nil
+}
+
+// GET /api/overlap counts the words shared by every pair of a comma separated list of tags
+func (s *server) overlap(w http.ResponseWriter, r *http.Request, granted access) (int, error) {
+	overlaps, err := s.db.TagOverlap(splitList(r.URL.Query().Get("tags")))
+	if errors.Is(err, store.ErrNotFound) {
+		return http.StatusNotFound, err
+	}
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	writeJSON(w, http.StatusOK, overlaps)
+	return http.StatusOK, nil
+}
+
+// GET /api/info returns the database system configuration
+func (s *server) info(w http.ResponseWriter, r *http.Request, granted access) (int, error) {
+	settings, err := s.db.Info()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if settings == nil {
+		settings = []store.Setting{}
+	}
+	writeJSON(w, http.StatusOK, settings)
+	return http.StatusOK, nil
+}
+
+// addRequest is the body of POST /api/words
+type addRequest struct {
+	Words []string `json:"words"`
+	Tags  []string `json:"tags"`
+}
+
+// POST /api/words adds words along with optional tags
+func (s *server) addWords(w http.ResponseWriter, r *http.Request, granted access) (int, error) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		return http.StatusUnsupportedMediaType, errors.New("the body must be sent as application/json")
+	}
+	var req addRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid body: %v", err)
+	}
+	var words []string
+	for _, word := range req.Words {
+		if word = strings.TrimSpace(word); word != "" {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return http.StatusBadRequest, errors.New("no words given")
+	}
+	tags := splitList(strings.Join(req.Tags, ","))
+	if err := s.db.AddWords(words, tags); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"words": len(words), "tags": tags})
+	return http.StatusOK, nil
+}
+
+// importResult is the response of POST /api/import
+type importResult struct {
+	Batch int64 `json:"batch"`
+	Words int64 `json:"words"`
+}
+
+// POST /api/import imports the request body, or the file fields of a multipart form, with the options of the import subcommand
+func (s *server) importWords(w http.ResponseWriter, r *http.Request, granted access) (int, error) {
+	params := r.URL.Query()
+	delimiter := params.Get("delim")
+	if unquoted, err := strconv.Unquote(`"` + delimiter + `"`); err == nil {
+		delimiter = unquoted
+	}
+	opts := store.ImportOptions{Tags: splitList(params.Get("tags")), Format: params.Get("format"), Delimiter: delimiter}
+	if opts.Format != "" && opts.Format != store.FormatPlain && opts.Format != store.FormatTagged {
+		return http.StatusBadRequest, fmt.Errorf("unknown import format %q, valid formats are %s, %s", opts.Format, store.FormatPlain, store.FormatTagged)
+	}
+	source := strings.TrimSpace(params.Get("source"))
+
+	var result importResult
+	// import a file, recording it under the batch of the request
+	importFile := func(body io.Reader, filename string) error {
+		var err error
+		if result.Batch == 0 {
+			if result.Batch, err = s.db.NewBatch(strings.TrimSpace("serve import " + r.URL.RawQuery)); err != nil {
+				return err
+			}
+		}
+		fileOpts := opts
+		fileOpts.BatchID, fileOpts.Source = result.Batch, source
+		if fileOpts.Source == "" {
+			fileOpts.Source = "<" + r.RemoteAddr + ">"
+			if filename != "" {
+				fileOpts.Source = filename
+			}
+		}
+		count, err := s.db.ImportReader(body, fileOpts)
+		result.Words += count
+		return err
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := importFile(r.Body, ""); err != nil {
+			return http.StatusBadRequest, err
+		}
+		writeJSON(w, http.StatusOK, result)
+		return http.StatusOK, nil
+	}
+
+	// the parts are imported as they are streamed, rather than spooled to disk first
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		if part.FormName() == "file" {
+			err = importFile(part, part.FileName())
+		}
+		part.Close()
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+	if result.Batch == 0 {
+		return http.StatusBadRequest, errors.New("no file field in the form")
+	}
+	writeJSON(w, http.StatusOK, result)
+	return http.StatusOK, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/proditis/orunmila/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// parse args of the serve subcommand and exec it
+func serveSubcmd(args []string) error {
+	serveCmd := flag.NewFlagSet("serve", flag.ContinueOnError)
+
+	serveCmd.SetOutput(flag.CommandLine.Output())
+
+	serveCmd.Usage = func() {
+		fmt.Fprint(serveCmd.Output(), "Serve the database over an HTTP API and a web UI\n\n")
+		fmt.Fprintln(serveCmd.Output(), "Usage of orunmila serve:")
+		fmt.Fprintf(serveCmd.Output(), "orunmila [-db <db_path>] [-debug] serve [-listen ADDRESS] [-tokens FILE] [-max-upload MB] [-no-ui]\n\n")
+		serveCmd.PrintDefaults()
+		fmt.Fprintln(serveCmd.Output(), "\nThe web UI is served under /, it browses the tags, builds queries and downloads the resulting lists")
+		fmt.Fprintln(serveCmd.Output(), "\nEndpoints")
+		fmt.Fprintln(serveCmd.Output(), "  GET  /api/search                 Stream the words matching the tags, q, source, since, until, sort, limit, checkpoint, ext, no-tag-ext, backup-variants, st and o parameters")
+		fmt.Fprintln(serveCmd.Output(), "  GET  /api/count                  Count the words matching the parameters of /api/search")
+		fmt.Fprintln(serveCmd.Output(), "  GET  /api/tags                   List the tags along with their number of words")
+		fmt.Fprintln(serveCmd.Output(), "  GET  /api/overlap                Count the words shared by every pair of the comma separated tags parameter")
+		fmt.Fprintln(serveCmd.Output(), "  GET  /api/info                   Display the database system configuration")
+		fmt.Fprintln(serveCmd.Output(), `  POST /api/words                  Add the words of a {"words":[...],"tags":[...]} application/json body`)
+		fmt.Fprintln(serveCmd.Output(), "  POST /api/import                 Import the body, or the file fields of a form, with the tags, format, delim and source parameters")
+		fmt.Fprintln(serveCmd.Output(), "\nThe tokens file holds one ro:TOKEN or rw:TOKEN line per token, sent as Authorization: Bearer TOKEN")
+		fmt.Fprintln(serveCmd.Output(), "ro tokens can search and list, rw tokens can also add, import and record checkpoints")
+		fmt.Fprintf(serveCmd.Output(), "Without tokens anyone can search and list, and add, import and record checkpoints with the %s header\n", writeHeader)
+		fmt.Fprintln(serveCmd.Output(), "\texample: orunmila serve -listen :8080 -tokens tokens.txt")
+		fmt.Fprintln(serveCmd.Output(), "\texample: curl -H 'Authorization: Bearer TOKEN' 'http://localhost:8080/api/search?tags=php&o=ndjson'")
+	}
+
+	var (
+		listenPtr    = serveCmd.String("listen", "localhost:8080", "the address to listen on, ie :8080 for every interface")
+		tokensPtr    = serveCmd.String("tokens", "", "a file of ro:TOKEN and rw:TOKEN lines, the bearer tokens accepted by the API (default: no authentication, the writes need the "+writeHeader+" header)")
+		maxUploadPtr = serveCmd.Int64("max-upload", defaultMaxUpload>>20, "the maximum size in MB of the add and import request bodies")
+		noUIPtr      = serveCmd.Bool("no-ui", false, "only serve the API, not the web UI")
+	)
+
+	err := serveCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	log.Debugln("[serveSubcmd] using db:", *dbPtr)
+	log.Debugln("[serveSubcmd] listen:", *listenPtr, "tokens:", *tokensPtr, "max upload:", *maxUploadPtr, "no ui:", *noUIPtr)
+
+	if serveCmd.NArg() > 0 {
+		serveCmd.Usage()
+		err = fmt.Errorf("serve takes no arguments")
+		log.Errorln("[serveSubcmd]", err)
+		return err
+	}
+	if *maxUploadPtr <= 0 {
+		err = fmt.Errorf("invalid -max-upload %d", *maxUploadPtr)
+		log.Errorln("[serveSubcmd]", err)
+		return err
+	}
+	srv := &server{maxUpload: *maxUploadPtr << 20, ui: !*noUIPtr}
+	if *tokensPtr != "" {
+		if srv.tokens, err = loadTokens(*tokensPtr); err != nil {
+			log.Errorln("[serveSubcmd]", err)
+			return err
+		}
+		log.Infof("[serveSubcmd] loaded %d tokens", len(srv.tokens))
+	} else {
+		log.Warnln("[serveSubcmd] no -tokens given, anyone reaching", *listenPtr, "can read the database and write to it with the", writeHeader, "header")
+	}
+
+	if srv.db, err = store.OpenShared(*dbPtr); err != nil {
+		log.Errorln("[serveSubcmd]", err)
+		return err
+	}
+	defer srv.db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err = listenAndServe(ctx, *listenPtr, srv.routes()); err != nil {
+		log.Errorln("[serveSubcmd]", err)
+	}
+	return err
+}
+
+// How long the running requests are given to complete on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// Serve handler on addr until ctx is done, then shut down gracefully
+func listenAndServe(ctx context.Context, addr string, handler http.Handler) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	httpServer := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	log.Infoln("[serve] listening on", listener.Addr())
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- httpServer.Serve(listener)
+	}()
+	select {
+	case err = <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Infoln("[serve] shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err = httpServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err = <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
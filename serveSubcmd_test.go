@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeSubcmd(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+
+	assert.Equal(t, flag.ErrHelp, serveSubcmd([]string{"-help"}))
+	assert.EqualError(t, serveSubcmd([]string{"now"}), "serve takes no arguments")
+	assert.EqualError(t, serveSubcmd([]string{"-max-upload", "0"}), "invalid -max-upload 0")
+	assert.Error(t, serveSubcmd([]string{"-tokens", filepath.Join(t.TempDir(), "missing.txt")}))
+
+	tokens := filepath.Join(t.TempDir(), "tokens.txt")
+	assert.NoError(t, os.WriteFile(tokens, []byte("rw:secret\n"), 0600))
+	assert.Error(t, serveSubcmd([]string{"-tokens", tokens, "-listen", "localhost:-1"}))
+
+	buf.Reset()
+	assert.Error(t, serveSubcmd([]string{"-listen", "localhost:-1"}))
+	assert.Contains(t, buf.String(), "no -tokens given")
+}
+
+func TestListenAndServe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	done := make(chan error, 1)
+	go func() {
+		done <- listenAndServe(ctx, "localhost:0", handler)
+	}()
+	cancel()
+	assert.NoError(t, <-done, "shutting down should not be an error")
+
+	assert.Error(t, listenAndServe(context.Background(), "localhost:-1", handler))
+}
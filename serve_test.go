@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/proditis/orunmila/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// Start a test server on the database given by -db, the returned function stops it and closes the database
+func newTestServer(t *testing.T, tokens []apiToken) (*httptest.Server, func()) {
+	t.Helper()
+	db, err := store.OpenShared(*dbPtr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer((&server{db: db, tokens: tokens, maxUpload: defaultMaxUpload, ui: true}).routes())
+	return ts, func() {
+		ts.Close()
+		db.Close()
+	}
+}
+
+// Send a request to a test server and return the status and body of its response, with the
+// writeHeader of the clients of a server without tokens
+func doRequest(t *testing.T, method, url, token, contentType string, body io.Reader) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(writeHeader, "1")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+func TestLoadTokens(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "tokens.txt")
+	assert.NoError(t, os.WriteFile(filename, []byte("# readers\nro:reader\n\nrw: writer \n"), 0600))
+	tokens, err := loadTokens(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, []apiToken{{"reader", accessRead}, {"writer", accessWrite}}, tokens)
+
+	assert.NoError(t, os.WriteFile(filename, []byte("admin:secret\n"), 0600))
+	_, err = loadTokens(filename)
+	assert.EqualError(t, err, filename+`:1: unknown access "admin", expected ro or rw`)
+
+	assert.NoError(t, os.WriteFile(filename, []byte("ro:reader\nsecret\n"), 0600))
+	_, err = loadTokens(filename)
+	assert.EqualError(t, err, filename+":2: expected ro:TOKEN or rw:TOKEN")
+
+	assert.NoError(t, os.WriteFile(filename, []byte("# nothing yet\n"), 0600))
+	_, err = loadTokens(filename)
+	assert.EqualError(t, err, filename+": no tokens found")
+
+	_, err = loadTokens(filepath.Join(dir, "missing.txt"))
+	assert.Error(t, err)
+}
+
+func TestServeAuth(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	ts, stop := newTestServer(t, []apiToken{{"reader", accessRead}, {"writer", accessWrite}})
+	defer stop()
+
+	add := `{"words":["admin"],"tags":["web"]}`
+	tests := []struct {
+		method, path, token string
+		status              int
+	}{
+		{http.MethodGet, "/api/search", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/search", "wrong", http.StatusUnauthorized},
+		{http.MethodGet, "/api/search", "reader", http.StatusOK},
+		{http.MethodGet, "/api/search", "writer", http.StatusOK},
+		{http.MethodGet, "/api/search?checkpoint=x", "reader", http.StatusForbidden},
+		{http.MethodGet, "/api/search?checkpoint=x", "writer", http.StatusOK},
+		{http.MethodGet, "/api/tags", "reader", http.StatusOK},
+		{http.MethodGet, "/api/count", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/count", "reader", http.StatusOK},
+		{http.MethodGet, "/api/overlap", "reader", http.StatusOK},
+		{http.MethodGet, "/api/info", "reader", http.StatusOK},
+		{http.MethodPost, "/api/words", "", http.StatusUnauthorized},
+		{http.MethodPost, "/api/words", "reader", http.StatusForbidden},
+		{http.MethodPost, "/api/words", "writer", http.StatusOK},
+		{http.MethodPost, "/api/import", "reader", http.StatusForbidden},
+		{http.MethodPost, "/api/search", "writer", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/words", "writer", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/missing", "writer", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		status, body := doRequest(t, test.method, ts.URL+test.path, test.token, "application/json", strings.NewReader(add))
+		assert.Equal(t, test.status, status, "%s %s with token %q: %s", test.method, test.path, test.token, body)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/tags", nil)
+	assert.NoError(t, err)
+	req.SetBasicAuth("writer", "writer")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "only bearer tokens are accepted")
+	assert.Equal(t, `Bearer realm="orunmila"`, resp.Header.Get("WWW-Authenticate"))
+}
+
+func TestServeSearch(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "php", "index.php", "config.php"})
+	addSubcmd([]string{"-tags", "drupal", "user/login"})
+	ts, stop := newTestServer(t, nil)
+	defer stop()
+
+	status, body := doRequest(t, http.MethodGet, ts.URL+"/api/search?tags=php", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "index.php\nconfig.php\n", body)
+
+	status, body = doRequest(t, http.MethodGet, ts.URL+"/api/search?q=php+or+drupal&sort=alpha&limit=2&st", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "config.php php\nindex.php php\n", body)
+
+	resp, err := http.Get(ts.URL + "/api/search?tags=drupal&o=ndjson")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	var records []store.Record
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var rec store.Record
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &rec))
+		records = append(records, rec)
+	}
+	assert.Len(t, records, 1)
+	assert.Equal(t, "user/login", records[0].Word)
+	assert.Equal(t, []string{"drupal"}, records[0].Tags)
+
+	status, body = doRequest(t, http.MethodGet, ts.URL+"/api/search?tags=drupal&ext=bak&no-tag-ext", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "user/login\nuser/login.bak\n", body)
+
+	status, body = doRequest(t, http.MethodGet, ts.URL+"/api/search?tags=php&limit=1&backup-variants=true", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "index.php\nindex.php~\n")
+
+	status, body = doRequest(t, http.MethodGet, ts.URL+"/api/search?tags=php&checkpoint=weekly", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "index.php\nconfig.php\n", body)
+	status, body = doRequest(t, http.MethodGet, ts.URL+"/api/search?tags=php&checkpoint=weekly", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "", body)
+
+	for _, query := range []string{"q=php+and", "sort=random", "limit=-1", "since=yesterday", "o=xml", "st=maybe", "ext=@missing"} {
+		status, body = doRequest(t, http.MethodGet, ts.URL+"/api/search?"+query, "", "", nil)
+		assert.Equal(t, http.StatusBadRequest, status, query)
+		assert.Contains(t, body, `"error":`, query)
+	}
+}
+
+func TestServeTagsAndInfo(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	ts, stop := newTestServer(t, nil)
+	defer stop()
+
+	status, body := doRequest(t, http.MethodGet, ts.URL+"/api/tags", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "[]\n", body)
+
+	addSubcmd([]string{"-tags", "php,web", "index.php"})
+	describeSubcmd([]string{"served words"})
+	status, body = doRequest(t, http.MethodGet, ts.URL+"/api/tags", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	var tags []store.TagInfo
+	assert.NoError(t, json.Unmarshal([]byte(body), &tags))
+	assert.Len(t, tags, 2)
+	assert.Equal(t, "php", tags[0].Name)
+	assert.Equal(t, int64(1), tags[0].Words)
+
+	status, body = doRequest(t, http.MethodGet, ts.URL+"/api/info", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	var settings []store.Setting
+	assert.NoError(t, json.Unmarshal([]byte(body), &settings))
+	assert.Contains(t, settings, store.Setting{Name: "description", Value: "served words"})
+}
+
+func TestServeCountAndOverlap(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "php,web", "index.php", "admin.php"})
+	addSubcmd([]string{"-tags", "web,api", "swagger.json", "admin.php"})
+	ts, stop := newTestServer(t, nil)
+	defer stop()
+
+	for query, want := range map[string]string{
+		"":                       `{"words":3}`,
+		"tags=web":               `{"words":3}`,
+		"q=web+and+not+api":      `{"words":1}`,
+		"q=php+and+api":          `{"words":1}`,
+		"tags=web&limit=2":       `{"words":2}`,
+		"tags=web&checkpoint=cp": `{"words":3}`,
+	} {
+		status, body := doRequest(t, http.MethodGet, ts.URL+"/api/count?"+query, "", "", nil)
+		assert.Equal(t, http.StatusOK, status, query)
+		assert.JSONEq(t, want, body, query)
+	}
+	status, body := doRequest(t, http.MethodGet, ts.URL+"/api/search?tags=web&checkpoint=cp", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, strings.Fields(body), 3, "counting should not record the checkpoint")
+	status, _ = doRequest(t, http.MethodGet, ts.URL+"/api/count?q=(web", "", "", nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// the count matches the length of the list, extensions and backup variants included
+	addSubcmd([]string{"-tags", "web", "login"})
+	assert.NoError(t, extSubcmd([]string{"set", "web", "bak,old"}))
+	assert.NoError(t, extSubcmd([]string{"link", "-tags", "web", "web"}))
+	for _, query := range []string{"tags=web", "tags=web&no-tag-ext", "tags=api&ext=php,inc", "tags=php&backup-variants", "tags=web&ext=@web&backup-variants&limit=1"} {
+		status, body = doRequest(t, http.MethodGet, ts.URL+"/api/search?"+query, "", "", nil)
+		assert.Equal(t, http.StatusOK, status, query)
+		lines := strings.Count(body, "\n")
+		status, body = doRequest(t, http.MethodGet, ts.URL+"/api/count?"+query, "", "", nil)
+		assert.Equal(t, http.StatusOK, status, query)
+		assert.JSONEq(t, fmt.Sprintf(`{"words":%d}`, lines), body, query)
+	}
+	status, body = doRequest(t, http.MethodGet, ts.URL+"/api/count?tags=web", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"words":6}`, body, "login is followed by login.bak and login.old")
+
+	status, body = doRequest(t, http.MethodGet, ts.URL+"/api/overlap?tags=php,web,api", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	var overlaps []store.TagOverlapInfo
+	assert.NoError(t, json.Unmarshal([]byte(body), &overlaps))
+	assert.Equal(t, []store.TagOverlapInfo{{Tag: "php", Other: "web", Words: 2}, {Tag: "php", Other: "api", Words: 1}, {Tag: "web", Other: "api", Words: 2}}, sortOverlaps(overlaps))
+
+	status, body = doRequest(t, http.MethodGet, ts.URL+"/api/overlap?tags=php", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "[]\n", body)
+	status, _ = doRequest(t, http.MethodGet, ts.URL+"/api/overlap?tags=php,missing", "", "", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+// Order overlaps by tag names, as the ones with the same number of words are ordered by tag ids
+func sortOverlaps(overlaps []store.TagOverlapInfo) []store.TagOverlapInfo {
+	order := map[string]int{"php": 0, "web": 1, "api": 2}
+	sort.Slice(overlaps, func(i, j int) bool {
+		a, b := overlaps[i], overlaps[j]
+		if order[a.Tag] != order[b.Tag] {
+			return order[a.Tag] < order[b.Tag]
+		}
+		return order[a.Other] < order[b.Other]
+	})
+	return overlaps
+}
+
+func TestServeWebUI(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	ts, stop := newTestServer(t, []apiToken{{"reader", accessRead}})
+	defer stop()
+
+	resp, err := http.Get(ts.URL + "/")
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "the UI itself needs no token")
+	assert.Contains(t, string(body), `<script src="app.js" defer></script>`)
+	assert.Equal(t, webCSP, resp.Header.Get("Content-Security-Policy"))
+
+	for path, contentType := range map[string]string{"/app.js": "javascript", "/style.css": "text/css"} {
+		resp, err := http.Get(ts.URL + path)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+		assert.Contains(t, resp.Header.Get("Content-Type"), contentType, path)
+	}
+
+	status, _ := doRequest(t, http.MethodPost, ts.URL+"/index.html", "", "text/plain", strings.NewReader("x"))
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+	status, _ = doRequest(t, http.MethodGet, ts.URL+"/missing.js", "", "", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	db, err := store.OpenShared(*dbPtr)
+	assert.NoError(t, err)
+	defer db.Close()
+	api := httptest.NewServer((&server{db: db, maxUpload: defaultMaxUpload}).routes())
+	defer api.Close()
+	status, _ = doRequest(t, http.MethodGet, api.URL+"/", "", "", nil)
+	assert.Equal(t, http.StatusNotFound, status, "-no-ui only serves the API")
+}
+
+// A server without tokens only takes writes with writeHeader, which the forms and scripts of other sites cannot send
+func TestServeWriteHeader(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	ts, stop := newTestServer(t, nil)
+	defer stop()
+
+	post := func(path, contentType, body string) (int, string) {
+		resp, err := http.Post(ts.URL+path, contentType, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return resp.StatusCode, string(data)
+	}
+	status, body := post("/api/words", "application/json", `{"words":["admin"]}`)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Contains(t, body, "this request needs the "+writeHeader+" header")
+	status, _ = post("/api/import", "text/plain", "admin\n")
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = post("/api/import", "application/x-www-form-urlencoded", "admin=1")
+	assert.Equal(t, http.StatusForbidden, status)
+
+	resp, err := http.Get(ts.URL + "/api/search?checkpoint=x")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp, err = http.Get(ts.URL + "/api/search")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// /api/words only takes JSON bodies
+	for _, contentType := range []string{"text/plain", "application/x-www-form-urlencoded", ""} {
+		status, _ = doRequest(t, http.MethodPost, ts.URL+"/api/words", "", contentType, strings.NewReader(`{"words":["admin"]}`))
+		assert.Equal(t, http.StatusUnsupportedMediaType, status, contentType)
+	}
+	status, _ = doRequest(t, http.MethodPost, ts.URL+"/api/words", "", "application/json; charset=utf-8", strings.NewReader(`{"words":["admin"]}`))
+	assert.Equal(t, http.StatusOK, status)
+	// only the last request wrote to the database
+	status, body = doRequest(t, http.MethodGet, ts.URL+"/api/search?st", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "admin \n", body)
+}
+
+func TestServeAddAndImport(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	ts, stop := newTestServer(t, nil)
+	defer stop()
+
+	status, body := doRequest(t, http.MethodPost, ts.URL+"/api/words", "", "application/json", strings.NewReader(`{"words":["admin"," login ",""],"tags":["web"]}`))
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"words":2,"tags":["web"]}`, body)
+	for _, bad := range []string{`{"words":[]}`, `{"word":"admin"}`, `words`} {
+		status, _ = doRequest(t, http.MethodPost, ts.URL+"/api/words", "", "application/json", strings.NewReader(bad))
+		assert.Equal(t, http.StatusBadRequest, status, bad)
+	}
+
+	status, body = doRequest(t, http.MethodPost, ts.URL+"/api/import?tags=api&source=https://example.com/api.txt", "", "text/plain", strings.NewReader("users\norders\n\n"))
+	assert.Equal(t, http.StatusOK, status)
+	var result importResult
+	assert.NoError(t, json.Unmarshal([]byte(body), &result))
+	assert.Equal(t, int64(2), result.Words)
+	assert.NotZero(t, result.Batch)
+
+	status, body = doRequest(t, http.MethodPost, ts.URL+"/api/import?format=tagged&delim=%7C", "", "text/plain", strings.NewReader("swagger.json|api,docs\n"))
+	assert.Equal(t, http.StatusOK, status, body)
+	status, _ = doRequest(t, http.MethodPost, ts.URL+"/api/import?format=xml", "", "text/plain", strings.NewReader("users\n"))
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	assert.NoError(t, mw.WriteField("comment", "ignored"))
+	for _, file := range [][2]string{{"a.txt", "alpha\nbeta\n"}, {"b.txt", "gamma\n"}} {
+		fw, err := mw.CreateFormFile("file", file[0])
+		assert.NoError(t, err)
+		_, err = fw.Write([]byte(file[1]))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, mw.Close())
+	status, body = doRequest(t, http.MethodPost, ts.URL+"/api/import?tags=greek", "", mw.FormDataContentType(), &form)
+	assert.Equal(t, http.StatusOK, status)
+	assert.NoError(t, json.Unmarshal([]byte(body), &result))
+	assert.Equal(t, int64(3), result.Words)
+
+	form.Reset()
+	mw = multipart.NewWriter(&form)
+	assert.NoError(t, mw.WriteField("comment", "no file"))
+	assert.NoError(t, mw.Close())
+	status, body = doRequest(t, http.MethodPost, ts.URL+"/api/import", "", mw.FormDataContentType(), &form)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "no file field in the form")
+
+	status, body = doRequest(t, http.MethodGet, ts.URL+"/api/search?q=web+or+api&sort=alpha&st", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "admin web\nlogin web\norders api\nswagger.json api,docs\nusers api\n", body)
+	status, body = doRequest(t, http.MethodGet, ts.URL+"/api/search?tags=greek&source=b.txt", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "gamma\n", body)
+
+	db := openStoreReadOnly()
+	defer db.Close()
+	batch, err := db.Batch(result.Batch)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), batch.Words)
+	assert.Equal(t, []string{"a.txt", "b.txt"}, batch.Sources)
+}
+
+func TestServeConcurrency(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "load", "seed"})
+	ts, stop := newTestServer(t, nil)
+	defer stop()
+
+	var wg sync.WaitGroup
+	statuses := make(chan int, 40)
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			status, _ := doRequest(t, http.MethodPost, ts.URL+"/api/import?tags=load", "", "text/plain", strings.NewReader(fmt.Sprintf("word%d\nshared\n", i)))
+			statuses <- status
+		}(i)
+		go func() {
+			defer wg.Done()
+			status, _ := doRequest(t, http.MethodGet, ts.URL+"/api/search?tags=load", "", "", nil)
+			statuses <- status
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+	for status := range statuses {
+		assert.Equal(t, http.StatusOK, status)
+	}
+
+	status, body := doRequest(t, http.MethodGet, ts.URL+"/api/search?tags=load", "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, strings.Fields(body), 22)
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BatchInfo describes an import or add run along with what it introduced
+type BatchInfo struct {
+	ID        int64     `json:"id"`
+	Command   string    `json:"command"`
+	CreatedAt time.Time `json:"created_at"`
+	// Words and Links count the words and associations introduced by the batch
+	Words int64 `json:"words"`
+	Links int64 `json:"links"`
+	// Hits counts the feedback hits credited to the words by the batch
+	Hits    int64    `json:"hits"`
+	Sources []string `json:"sources"`
+}
+
+const batchInfoQuery = `select b.id,b.command,b.created_at,
+	(select count(*) from words where batch_id=b.id),
+	(select count(*) from wt where batch_id=b.id),
+	(select ifnull(sum(n),0) from batch_hits where batch_id=b.id and tag_id=0),
+	ifnull((select group_concat(path,char(10)) from (select path from sources where batch_id=b.id order by id)),'')
+	from batches as b`
+
+func scanBatchInfo(row interface{ Scan(...interface{}) error }) (BatchInfo, error) {
+	var info BatchInfo
+	var createdAt int64
+	var sources string
+	if err := row.Scan(&info.ID, &info.Command, &createdAt, &info.Words, &info.Links, &info.Hits, &sources); err != nil {
+		return info, err
+	}
+	info.CreatedAt = time.Unix(createdAt, 0)
+	info.Sources = []string{}
+	if sources != "" {
+		info.Sources = strings.Split(sources, "\n")
+	}
+	return info, nil
+}
+
+// Record the start of a run, described by command, and return its batch id
+func newBatch(tx *sql.Tx, command string) (int64, error) {
+	result, err := tx.Exec("insert into batches(command,created_at) values(?,?)", command, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// NewBatch records the start of an import run, described by command, and returns its id.
+// Pass the id to every ImportReader call of the run through ImportOptions.BatchID.
+func (s *Store) NewBatch(command string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := newBatch(tx, command)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// Batches lists the batches along with what they introduced, oldest first
+func (s *Store) Batches() ([]BatchInfo, error) {
+	rows, err := s.db.Query(batchInfoQuery + ` order by b.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batches := []BatchInfo{}
+	for rows.Next() {
+		info, err := scanBatchInfo(rows)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, info)
+	}
+	return batches, rows.Err()
+}
+
+// Batch returns the details of a single batch
+func (s *Store) Batch(id int64) (BatchInfo, error) {
+	info, err := scanBatchInfo(s.db.QueryRow(batchInfoQuery+` where b.id=?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return info, fmt.Errorf("batch %d: %w", id, ErrNotFound)
+	}
+	return info, err
+}
+
+// The statements undoing a batch, in order. Words and tags introduced by the batch that
+// were linked by later batches are kept and handed over to the earliest of those batches.
+// The first statement removes the associations and the fourth one the words.
+var undoBatchStmts = []string{
+	`delete from wt where batch_id=?1`,
+	`delete from word_sources where source_id in (select id from sources where batch_id=?1)`,
+	`delete from sources where batch_id=?1`,
+	`delete from words where batch_id=?1 and not exists (select 1 from wt where wt.word_id=words.id) and not exists (select 1 from word_sources as ws where ws.word_id=words.id)`,
+	`update words set batch_id=(select min(b) from (select batch_id as b from wt where wt.word_id=words.id union all select s.batch_id from word_sources as ws join sources as s on s.id=ws.source_id where ws.word_id=words.id)) where batch_id=?1`,
+	`delete from tags where batch_id=?1 and description='' and not exists (select 1 from wt where wt.tag_id=tags.id) and not exists (select 1 from tag_ext_sets as te where te.tag_id=tags.id)`,
+	`update tags set batch_id=(select min(batch_id) from wt where wt.tag_id=tags.id) where batch_id=?1`,
+	`update words set hits=hits-(select n from batch_hits as bh where bh.batch_id=?1 and bh.word_id=words.id and bh.tag_id=0) where id in (select word_id from batch_hits where batch_id=?1 and tag_id=0)`,
+	`update wt set hits=hits-(select n from batch_hits as bh where bh.batch_id=?1 and bh.word_id=wt.word_id and bh.tag_id=wt.tag_id) where exists (select 1 from batch_hits as bh where bh.batch_id=?1 and bh.word_id=wt.word_id and bh.tag_id=wt.tag_id)`,
+	`delete from batch_hits where batch_id=?1`,
+	`delete from batches where id=?1`,
+}
+
+// UndoBatch removes the words, associations and sources introduced by a batch, along with the feedback
+// hits it credited, then the batch itself. Words and associations that existed before the batch are kept.
+// It returns the batch along with the number of words, associations and hits actually removed.
+func (s *Store) UndoBatch(id int64) (BatchInfo, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return BatchInfo{}, err
+	}
+	defer tx.Rollback()
+
+	info, err := scanBatchInfo(tx.QueryRow(batchInfoQuery+` where b.id=?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return info, fmt.Errorf("batch %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return info, err
+	}
+	for i, query := range undoBatchStmts {
+		result, err := tx.Exec(query, id)
+		if err != nil {
+			return info, err
+		}
+		n, _ := result.RowsAffected()
+		log.Debugf("[UndoBatch] %s => %d rows", query, n)
+		switch i {
+		case 0:
+			info.Links = n
+		case 3:
+			info.Words = n
+		}
+	}
+	return info, tx.Commit()
+}
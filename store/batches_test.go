@@ -0,0 +1,87 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUndoBatch(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"admin", "login"}, []string{"php"}))
+
+	batchID, err := s.NewBatch("import -tags php,typo list.txt")
+	assert.NoError(t, err)
+	_, err = s.ImportReader(strings.NewReader("admin\nbackup\nconfig\n"), ImportOptions{Tags: []string{"php", "typo"}, Source: "list.txt", BatchID: batchID})
+	assert.NoError(t, err)
+	_, err = s.ImportReader(strings.NewReader("debug\n"), ImportOptions{Source: "other.txt", BatchID: batchID})
+	assert.NoError(t, err)
+
+	// a later batch links one of the words introduced by the batch
+	assert.NoError(t, s.AddWords([]string{"config"}, []string{"later"}))
+
+	batches, err := s.Batches()
+	assert.NoError(t, err)
+	if assert.Len(t, batches, 3) {
+		batch := batches[1]
+		assert.Equal(t, batchID, batch.ID)
+		assert.Equal(t, "import -tags php,typo list.txt", batch.Command)
+		assert.Equal(t, int64(3), batch.Words)
+		assert.Equal(t, int64(5), batch.Links)
+		assert.Equal(t, []string{"list.txt", "other.txt"}, batch.Sources)
+		assert.Equal(t, "add", batches[0].Command)
+		assert.Equal(t, []string{AddSource}, batches[0].Sources)
+	}
+
+	undone, err := s.UndoBatch(batchID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), undone.Words)
+	assert.Equal(t, int64(5), undone.Links)
+
+	var names []string
+	for _, rec := range search(t, s, SearchOptions{}) {
+		names = append(names, rec.Word+":"+strings.Join(rec.Tags, ","))
+	}
+	assert.Equal(t, []string{"admin:php", "login:php", "config:later"}, names)
+	_, err = s.Tag("typo")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Tag("php")
+	assert.NoError(t, err)
+
+	info, err := s.Word("admin")
+	assert.NoError(t, err)
+	assert.Len(t, info.Sources, 1)
+
+	// config now belongs to the later batch, undoing it removes the word
+	batches, err = s.Batches()
+	assert.NoError(t, err)
+	if assert.Len(t, batches, 2) {
+		assert.Equal(t, int64(1), batches[1].Words)
+		undone, err = s.UndoBatch(batches[1].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), undone.Words)
+	}
+	assert.Equal(t, int64(2), countRows(t, s, "words"))
+
+	_, err = s.UndoBatch(batchID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Batch(batchID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestImportReaderNewBatch(t *testing.T) {
+	s := newTestStore(t)
+	_, err := s.ImportReader(strings.NewReader("admin\n"), ImportOptions{Source: "a.txt"})
+	assert.NoError(t, err)
+	_, err = s.ImportReader(strings.NewReader("admin\n"), ImportOptions{Source: "b.txt"})
+	assert.NoError(t, err)
+
+	batches, err := s.Batches()
+	assert.NoError(t, err)
+	if assert.Len(t, batches, 2) {
+		assert.Equal(t, "a.txt", batches[0].Command)
+		assert.Equal(t, int64(1), batches[0].Words)
+		assert.Equal(t, int64(0), batches[1].Words)
+	}
+}
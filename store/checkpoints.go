@@ -0,0 +1,86 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CheckpointInfo describes a search checkpoint along with the number of words it returned
+type CheckpointInfo struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Words     int64     `json:"words"`
+}
+
+// Create the checkpoint when it does not exist, mark it as used and return its id
+func ensureCheckpoint(tx *sql.Tx, name string) (int64, error) {
+	now := time.Now().Unix()
+	if _, err := tx.Exec("insert or ignore into checkpoints(name,created_at,updated_at) values(?,?,?)", name, now, now); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("update checkpoints set updated_at=? where name=?", now, name); err != nil {
+		return 0, err
+	}
+	var id int64
+	err := tx.QueryRow("select id from checkpoints where name=?", name).Scan(&id)
+	return id, err
+}
+
+// Compile a filter skipping the words already returned under a checkpoint
+func checkpointSQL(col string, name string, args *[]interface{}) string {
+	*args = append(*args, name)
+	return col + " NOT IN (SELECT cw.word_id FROM checkpoint_words AS cw JOIN checkpoints AS c ON c.id=cw.checkpoint_id WHERE c.name=?)"
+}
+
+// Checkpoints lists the search checkpoints along with their word counts, ordered by name
+func (s *Store) Checkpoints() ([]CheckpointInfo, error) {
+	rows, err := s.db.Query("select c.id,c.name,c.created_at,c.updated_at,(select count(*) from checkpoint_words where checkpoint_id=c.id) from checkpoints as c order by c.name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checkpoints := []CheckpointInfo{}
+	for rows.Next() {
+		var info CheckpointInfo
+		var createdAt, updatedAt int64
+		if err = rows.Scan(&info.ID, &info.Name, &createdAt, &updatedAt, &info.Words); err != nil {
+			return nil, err
+		}
+		info.CreatedAt, info.UpdatedAt = time.Unix(createdAt, 0), time.Unix(updatedAt, 0)
+		checkpoints = append(checkpoints, info)
+	}
+	return checkpoints, rows.Err()
+}
+
+// ResetCheckpoint deletes a checkpoint, so that its next search returns every word again.
+// It returns the number of words the checkpoint held.
+func (s *Store) ResetCheckpoint(name string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow("select id from checkpoints where name=?", name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("checkpoint %q: %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec("delete from checkpoint_words where checkpoint_id=?", id)
+	if err != nil {
+		return 0, err
+	}
+	words, _ := result.RowsAffected()
+	if _, err = tx.Exec("delete from checkpoints where id=?", id); err != nil {
+		return 0, err
+	}
+	return words, tx.Commit()
+}
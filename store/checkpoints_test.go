@@ -0,0 +1,61 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchCheckpoint(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"admin", "login"}, []string{"x"}))
+	assert.NoError(t, s.AddWords([]string{"other"}, []string{"y"}))
+
+	expr, _ := ParseQuery("x")
+	words := func(checkpoint string) []string {
+		var names []string
+		for _, rec := range search(t, s, SearchOptions{Query: expr, Checkpoint: checkpoint}) {
+			names = append(names, rec.Word)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"admin", "login"}, words("program"))
+	assert.Empty(t, words("program"))
+
+	// new words and words newly tagged are returned the next time around
+	assert.NoError(t, s.AddWords([]string{"backup", "other", "admin"}, []string{"x"}))
+	assert.Equal(t, []string{"other", "backup"}, words("program"))
+	assert.Empty(t, words("program"))
+	assert.Equal(t, []string{"admin", "login", "other", "backup"}, words("another"))
+
+	// a failed search records nothing
+	assert.NoError(t, s.AddWords([]string{"config"}, []string{"x"}))
+	var peeked []string
+	assert.NoError(t, s.Peek(SearchOptions{Query: expr, Checkpoint: "program"}, func(rec Record) error {
+		peeked = append(peeked, rec.Word)
+		return nil
+	}))
+	assert.Equal(t, []string{"config"}, peeked, "peeking records nothing either")
+	err := s.Search(SearchOptions{Query: expr, Checkpoint: "program"}, func(Record) error { return errors.New("broken pipe") })
+	assert.EqualError(t, err, "broken pipe")
+	assert.Equal(t, []string{"config"}, words("program"))
+
+	checkpoints, err := s.Checkpoints()
+	assert.NoError(t, err)
+	if assert.Len(t, checkpoints, 2) {
+		assert.Equal(t, "another", checkpoints[0].Name)
+		assert.Equal(t, int64(4), checkpoints[0].Words)
+		assert.Equal(t, "program", checkpoints[1].Name)
+		assert.Equal(t, int64(5), checkpoints[1].Words)
+	}
+
+	// removed words leave their checkpoints
+	_, err = s.RemoveWords([]string{"admin"})
+	assert.NoError(t, err)
+	forgotten, err := s.ResetCheckpoint("program")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), forgotten)
+	_, err = s.ResetCheckpoint("program")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// The magic bytes of the supported compression formats
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	// a bzip2 stream goes on with its block size, 1 to 9, and the magic of its first block or
+	// the end of stream magic when it is empty
+	bzip2BlockMagic = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	bzip2EndMagic   = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+	xzMagic         = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	zstdMagic       = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Compression returns the name of the compression format r starts with, or an empty string for
+// uncompressed content, along with a reader that still holds the peeked bytes
+func Compression(r io.Reader) (string, io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(bzip2Magic) + 1 + len(bzip2BlockMagic))
+	if err != nil && err != io.EOF {
+		return "", br, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return "gzip", br, nil
+	case isBzip2(magic):
+		return "bzip2", br, nil
+	case bytes.HasPrefix(magic, xzMagic):
+		return "xz", br, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		return "zstd", br, nil
+	}
+	return "", br, nil
+}
+
+// A plain text starting with BZh is not taken for bzip2, the block size and block magic must follow
+func isBzip2(magic []byte) bool {
+	if !bytes.HasPrefix(magic, bzip2Magic) || len(magic) < len(bzip2Magic)+1+len(bzip2BlockMagic) {
+		return false
+	}
+	if size := magic[len(bzip2Magic)]; size < '1' || size > '9' {
+		return false
+	}
+	block := magic[len(bzip2Magic)+1:]
+	return bytes.HasPrefix(block, bzip2BlockMagic) || bytes.HasPrefix(block, bzip2EndMagic)
+}
+
+// Decompress detects gzip, bzip2, xz and zstd content from its magic bytes and returns a reader
+// of the decompressed data, uncompressed content is returned as is
+func Decompress(r io.Reader) (io.ReadCloser, error) {
+	format, r, err := Compression(r)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "gzip":
+		return gzip.NewReader(r)
+	case "bzip2":
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case "xz":
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xr), nil
+	case "zstd":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return io.NopCloser(r), nil
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecompress(t *testing.T) {
+	tests := map[string]string{
+		"words.txt.gz":  "gzip",
+		"words.txt.bz2": "bzip2",
+		"words.txt.xz":  "xz",
+		"words.txt.zst": "zstd",
+	}
+	for fixture, format := range tests {
+		file, err := os.Open(filepath.Join("testdata", fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := Compression(file)
+		assert.NoError(t, err)
+		assert.Equalf(t, format, got, `Compression(%s)`, fixture)
+
+		_, err = file.Seek(0, io.SeekStart)
+		assert.NoError(t, err)
+		r, err := Decompress(file)
+		if assert.NoErrorf(t, err, `Decompress(%s)`, fixture) {
+			data, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equalf(t, "alpha\nbeta\ngamma\n", string(data), `Decompress(%s)`, fixture)
+			r.Close()
+		}
+		file.Close()
+	}
+}
+
+func TestDecompressPlain(t *testing.T) {
+	for _, input := range []string{"", "a", "plain\ntext\n", "BZh\n", "BZh9\nBZhelp\n", "BZh91AY&SX\n"} {
+		format, _, err := Compression(strings.NewReader(input))
+		assert.NoError(t, err)
+		assert.Equal(t, "", format)
+
+		r, err := Decompress(strings.NewReader(input))
+		assert.NoError(t, err)
+		data, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, input, string(data))
+	}
+}
+
+func TestImportCompressed(t *testing.T) {
+	s := newTestStore(t)
+	for _, fixture := range []string{"words.txt.gz", "words.txt.bz2", "words.txt.xz", "words.txt.zst"} {
+		file, err := os.Open(filepath.Join("testdata", fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		count, err := s.ImportReader(file, ImportOptions{Tags: []string{fixture}})
+		file.Close()
+		assert.NoErrorf(t, err, `ImportReader(%s)`, fixture)
+		assert.Equalf(t, int64(3), count, `ImportReader(%s)`, fixture)
+	}
+	assert.Equal(t, int64(3), countRows(t, s, "words"))
+	assert.Equal(t, int64(3*4), countRows(t, s, "wt"))
+}
+
+func TestDecompressEmptyBzip2(t *testing.T) {
+	// the output of bzip2 for an empty input, the end of stream magic right after the header
+	input := "BZh9\x17\x72\x45\x38\x50\x90\x00\x00\x00\x00"
+	format, _, err := Compression(strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Equal(t, "bzip2", format)
+
+	r, err := Decompress(strings.NewReader(input))
+	assert.NoError(t, err)
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "", string(data))
+}
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ExtSetInfo describes a named set of file extensions along with the tags it is linked to
+type ExtSetInfo struct {
+	ID         int64    `json:"id"`
+	Name       string   `json:"name"`
+	Extensions []string `json:"extensions"`
+	Tags       []string `json:"tags"`
+}
+
+// NormalizeExtensions trims the spaces and leading dots of a list of extensions,
+// dropping the empty and duplicate ones
+func NormalizeExtensions(exts []string) []string {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, ext := range exts {
+		ext = strings.TrimLeft(strings.TrimSpace(ext), ".")
+		if ext != "" && !seen[ext] {
+			seen[ext] = true
+			normalized = append(normalized, ext)
+		}
+	}
+	return normalized
+}
+
+// Split the extensions column of ext_sets
+func splitExtensions(exts string) []string {
+	if exts == "" {
+		return []string{}
+	}
+	return strings.Split(exts, ",")
+}
+
+// Return the id of an existing extension set
+func extSetID(q queryer, name string) (int64, error) {
+	var id int64
+	err := q.QueryRow("select id from ext_sets where name=?", name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("extension set %q: %w", name, ErrNotFound)
+	}
+	return id, err
+}
+
+// SetExtSet creates an extension set, or replaces the extensions of an existing one
+func (s *Store) SetExtSet(name string, exts []string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("the extension set name can not be empty")
+	}
+	exts = NormalizeExtensions(exts)
+	if len(exts) == 0 {
+		return fmt.Errorf("extension set %q needs at least one extension", name)
+	}
+	_, err := s.db.Exec("insert into ext_sets(name,extensions) values(?,?) on conflict(name) do update set extensions=excluded.extensions", name, strings.Join(exts, ","))
+	return err
+}
+
+// ExtSets lists the extension sets along with their tags, ordered by name
+func (s *Store) ExtSets() ([]ExtSetInfo, error) {
+	rows, err := s.db.Query(`select e.id,e.name,e.extensions,
+		ifnull((select group_concat(name) from (select t.name from tags as t join tag_ext_sets as te on te.tag_id=t.id where te.ext_set_id=e.id order by t.name)),'')
+		from ext_sets as e order by e.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sets := []ExtSetInfo{}
+	for rows.Next() {
+		var set ExtSetInfo
+		var exts, tags string
+		if err = rows.Scan(&set.ID, &set.Name, &exts, &tags); err != nil {
+			return nil, err
+		}
+		set.Extensions = splitExtensions(exts)
+		set.Tags = []string{}
+		if tags != "" {
+			set.Tags = strings.Split(tags, ",")
+		}
+		sets = append(sets, set)
+	}
+	return sets, rows.Err()
+}
+
+// ExtSetExtensions returns the extensions of a set
+func (s *Store) ExtSetExtensions(name string) ([]string, error) {
+	var exts string
+	err := s.db.QueryRow("select extensions from ext_sets where name=?", name).Scan(&exts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("extension set %q: %w", name, ErrNotFound)
+	}
+	return splitExtensions(exts), err
+}
+
+// DeleteExtSet deletes an extension set along with its tag links
+func (s *Store) DeleteExtSet(name string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	id, err := extSetID(tx, name)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec("delete from tag_ext_sets where ext_set_id=?", id); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("delete from ext_sets where id=?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// LinkExtSet links an extension set to tags, so that searching them adds its extensions.
+// Missing tags are created. It returns the number of new links.
+func (s *Store) LinkExtSet(name string, tags []string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := extSetID(tx, name)
+	if err != nil {
+		return 0, err
+	}
+	tagIDs, err := ensureTags(tx, tags, 0)
+	if err != nil {
+		return 0, err
+	}
+	var added int64
+	for _, tagID := range tagIDs {
+		result, err := tx.Exec("insert or ignore into tag_ext_sets(tag_id,ext_set_id) values(?,?)", tagID, id)
+		if err != nil {
+			return 0, err
+		}
+		n, _ := result.RowsAffected()
+		added += n
+	}
+	return added, tx.Commit()
+}
+
+// UnlinkExtSet removes the links of an extension set to tags. It returns the number of links removed.
+func (s *Store) UnlinkExtSet(name string, tags []string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := extSetID(tx, name)
+	if err != nil {
+		return 0, err
+	}
+	var removed int64
+	for _, tag := range tags {
+		result, err := tx.Exec("delete from tag_ext_sets where ext_set_id=? and tag_id=(select id from tags where name=?)", id, tag)
+		if err != nil {
+			return 0, err
+		}
+		n, _ := result.RowsAffected()
+		removed += n
+	}
+	return removed, tx.Commit()
+}
+
+// TagExtensions returns the extensions of the sets linked to any of the tags, in set name order
+func (s *Store) TagExtensions(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return []string{}, nil
+	}
+	args := make([]interface{}, len(tags))
+	for i, tag := range tags {
+		args[i] = tag
+	}
+	rows, err := s.db.Query(`select e.extensions from ext_sets as e where e.id in
+		(select te.ext_set_id from tag_ext_sets as te join tags as t on t.id=te.tag_id where t.name in (?`+strings.Repeat(",?", len(tags)-1)+`))
+		order by e.name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exts []string
+	for rows.Next() {
+		var set string
+		if err = rows.Scan(&set); err != nil {
+			return nil, err
+		}
+		exts = append(exts, splitExtensions(set)...)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return NormalizeExtensions(exts), nil
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeExtensions(t *testing.T) {
+	assert.Equal(t, []string{"php", "tar.gz", "bak"}, NormalizeExtensions([]string{" .php", "tar.gz", "", ".", "php", "bak"}))
+	assert.Equal(t, []string{}, NormalizeExtensions(nil))
+}
+
+func TestExtSets(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"index"}, []string{"php"}))
+	assert.NoError(t, s.SetExtSet("php", []string{"php", ".php5", "phtml"}))
+	assert.NoError(t, s.SetExtSet("backup", []string{"bak", "old"}))
+	assert.NoError(t, s.SetExtSet("backup", []string{"bak", "old", "orig"}))
+	assert.Error(t, s.SetExtSet("empty", []string{"."}))
+	assert.Error(t, s.SetExtSet(" ", []string{"bak"}))
+
+	added, err := s.LinkExtSet("php", []string{"php", "laravel"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), added)
+	added, err = s.LinkExtSet("backup", []string{"php"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), added)
+	_, err = s.LinkExtSet("missing", []string{"php"})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	sets, err := s.ExtSets()
+	assert.NoError(t, err)
+	assert.Equal(t, []ExtSetInfo{
+		{ID: 2, Name: "backup", Extensions: []string{"bak", "old", "orig"}, Tags: []string{"php"}},
+		{ID: 1, Name: "php", Extensions: []string{"php", "php5", "phtml"}, Tags: []string{"laravel", "php"}},
+	}, sets)
+
+	exts, err := s.TagExtensions([]string{"php"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bak", "old", "orig", "php", "php5", "phtml"}, exts)
+	exts, err = s.TagExtensions([]string{"laravel", "other"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"php", "php5", "phtml"}, exts)
+	exts, err = s.ExtSetExtensions("backup")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bak", "old", "orig"}, exts)
+	_, err = s.ExtSetExtensions("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	removed, err := s.UnlinkExtSet("backup", []string{"php", "laravel"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	// merged tags keep their links, deleted tags lose them
+	_, err = s.MergeTags([]string{"laravel"}, "symfony")
+	assert.NoError(t, err)
+	exts, err = s.TagExtensions([]string{"symfony"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"php", "php5", "phtml"}, exts)
+	_, err = s.DeleteTag("symfony")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), countRows(t, s, "tag_ext_sets"))
+
+	assert.NoError(t, s.DeleteExtSet("php"))
+	assert.ErrorIs(t, s.DeleteExtSet("php"), ErrNotFound)
+	assert.Equal(t, int64(0), countRows(t, s, "tag_ext_sets"))
+	assert.Equal(t, int64(1), countRows(t, s, "ext_sets"))
+}
+
+func TestUndoBatchKeepsLinkedTags(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"index"}, []string{"php"}))
+	assert.NoError(t, s.SetExtSet("php", []string{"php"}))
+	_, err := s.LinkExtSet("php", []string{"php"})
+	assert.NoError(t, err)
+
+	_, err = s.UndoBatch(1)
+	assert.NoError(t, err)
+	exts, err := s.TagExtensions([]string{"php"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"php"}, exts)
+}
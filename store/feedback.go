@@ -0,0 +1,99 @@
+package store
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+)
+
+// HitsInfo sums up a RecordHits call
+type HitsInfo struct {
+	// Words counts the known words credited with hits and Links the associations created for them
+	Words int64 `json:"words"`
+	Links int64 `json:"links"`
+	// Unknown lists the words that are not in the database, in byte order
+	Unknown []string `json:"unknown"`
+}
+
+// RecordHits adds the number of times fuzzers found each word to the hits of the words, and to
+// the hits of their associations with tags when some are given. Missing associations and tags
+// are created within the batch, a new one when batchID is zero, which records the hits so that undoing
+// it takes them back. Hits count towards the score of the words.
+func (s *Store) RecordHits(hits map[string]int64, tags []string, batchID int64) (HitsInfo, error) {
+	info := HitsInfo{Unknown: []string{}}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return info, err
+	}
+	defer tx.Rollback()
+
+	if batchID == 0 {
+		if batchID, err = newBatch(tx, "feedback"); err != nil {
+			return info, err
+		}
+	}
+	tagIDs, err := ensureTags(tx, tags, batchID)
+	if err != nil {
+		return info, err
+	}
+
+	stmts := make(map[string]*sql.Stmt)
+	for name, query := range map[string]string{
+		"word": "update words set hits=hits+? where id=?",
+		"link": "insert or ignore into wt(word_id,tag_id,batch_id,created_at) values(?,?,?,?)",
+		"hits": "update wt set hits=hits+? where word_id=? and tag_id=?",
+		"time": "update words set updated_at=? where id=?",
+		// the hits of the words are recorded under tag 0
+		"batch": "insert into batch_hits(batch_id,word_id,tag_id,n) values(?,?,?,?) on conflict(batch_id,word_id,tag_id) do update set n=n+excluded.n",
+	} {
+		if stmts[name], err = tx.Prepare(query); err != nil {
+			return info, err
+		}
+		defer stmts[name].Close()
+	}
+
+	// credit the words in order, so that the created associations are reproducible
+	words := make([]string, 0, len(hits))
+	for word := range hits {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	now := time.Now().Unix()
+	for _, word := range words {
+		id, err := wordID(tx, word)
+		if err != nil {
+			return info, err
+		}
+		if id <= 0 {
+			info.Unknown = append(info.Unknown, word)
+			continue
+		}
+		if _, err = stmts["word"].Exec(hits[word], id); err != nil {
+			return info, err
+		}
+		if _, err = stmts["batch"].Exec(batchID, id, 0, hits[word]); err != nil {
+			return info, err
+		}
+		info.Words++
+		for _, tag := range tags {
+			result, err := stmts["link"].Exec(id, tagIDs[tag], batchID, now)
+			if err != nil {
+				return info, err
+			}
+			if n, _ := result.RowsAffected(); n > 0 {
+				info.Links += n
+				if _, err = stmts["time"].Exec(now, id); err != nil {
+					return info, err
+				}
+			}
+			if _, err = stmts["hits"].Exec(hits[word], id, tagIDs[tag]); err != nil {
+				return info, err
+			}
+			if _, err = stmts["batch"].Exec(batchID, id, tagIDs[tag], hits[word]); err != nil {
+				return info, err
+			}
+		}
+	}
+	return info, tx.Commit()
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordHits(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"admin", "login", "other"}, []string{"php"}))
+
+	info, err := s.RecordHits(map[string]int64{"login": 2, "admin": 1, "missing": 1, "zmissing": 3}, []string{"php", "drupal"}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, HitsInfo{Words: 2, Links: 2, Unknown: []string{"missing", "zmissing"}}, info)
+	_, err = s.RecordHits(map[string]int64{"login": 1}, nil, 0)
+	assert.NoError(t, err)
+
+	word, err := s.Word("login")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), word.Hits)
+	// 3 hits and 1 source
+	assert.Equal(t, float64(4), word.Score)
+	assert.Equal(t, map[string]float64{"drupal": 2, "php": 2}, word.TagScores)
+
+	// the hits rank the words and the associations created can be undone
+	drupal, _ := ParseQuery("drupal")
+	var names []string
+	for _, rec := range search(t, s, SearchOptions{Query: drupal, Sort: SortScore}) {
+		names = append(names, rec.Word)
+	}
+	assert.Equal(t, []string{"login", "admin"}, names)
+
+	batches, err := s.Batches()
+	assert.NoError(t, err)
+	if assert.Len(t, batches, 3) {
+		assert.Equal(t, "feedback", batches[1].Command)
+		assert.Equal(t, int64(2), batches[1].Links)
+		assert.Equal(t, int64(3), batches[1].Hits)
+	}
+	undone, err := s.UndoBatch(batches[1].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), undone.Links)
+	assert.Equal(t, int64(3), undone.Hits)
+	_, err = s.Tag("drupal")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, int64(3), countRows(t, s, "words"))
+
+	// the hits of the undone batch are taken back, the ones of the later batch are kept
+	word, err = s.Word("login")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), word.Hits)
+	assert.Equal(t, map[string]float64{}, word.TagScores)
+	word, err = s.Word("admin")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), word.Hits)
+	assert.Equal(t, int64(0), countRows(t, s, "batch_hits where batch_id="+strconv.FormatInt(batches[1].ID, 10)))
+
+	undone, err = s.UndoBatch(batches[2].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), undone.Hits)
+	word, err = s.Word("login")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), word.Hits)
+	assert.Equal(t, int64(0), countRows(t, s, "batch_hits"))
+}
@@ -0,0 +1,338 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The formats of the files read by ImportReader
+const (
+	// FormatPlain holds one word per line
+	FormatPlain = "plain"
+	// FormatTagged holds one word per line, optionally followed by a delimiter and a comma separated list of tags
+	FormatTagged = "tagged"
+)
+
+// DefaultBatchSize is the number of lines committed per transaction when ImportOptions.BatchSize is not set
+const DefaultBatchSize = 50000
+
+// DefaultDelimiter separates the words from their tags in the tagged format
+const DefaultDelimiter = "\t"
+
+// ImportOptions control how ImportReader reads and tags the words
+type ImportOptions struct {
+	// Tags are associated with every imported word
+	Tags []string
+	// Format is either FormatPlain, the default, or FormatTagged
+	Format string
+	// Delimiter separates the words from their inline tags, DefaultDelimiter when empty
+	Delimiter string
+	// BatchSize is the number of lines committed per transaction, DefaultBatchSize when zero
+	BatchSize int
+	// Source is the path or URL the lines come from, recorded along with the words. Nothing is recorded when empty.
+	Source string
+	// BatchID groups the import with the others of the same run, see NewBatch. A new batch is created when zero.
+	BatchID int64
+}
+
+// ParseTaggedLine splits a line of the tagged format into the word and its tags.
+// The last occurrence of the delimiter is used, so that words may contain it.
+func ParseTaggedLine(line string, delimiter string) (string, []string) {
+	i := strings.LastIndex(line, delimiter)
+	if delimiter == "" || i < 0 {
+		return strings.TrimSpace(line), nil
+	}
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(line[i+len(delimiter):], ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return strings.TrimSpace(line[:i]), tags
+}
+
+// FormatTaggedLine joins a word and its tags into a line of the tagged format
+func FormatTaggedLine(word string, tags []string, delimiter string) string {
+	if len(tags) == 0 {
+		return word
+	}
+	return word + delimiter + strings.Join(tags, ",")
+}
+
+// ImportReader imports one word per line from r, associated with the tags of the options.
+// Content compressed with gzip, bzip2, xz or zstd is detected and decompressed.
+// It returns the number of lines imported.
+func (s *Store) ImportReader(r io.Reader, opts ImportOptions) (int64, error) {
+	hash := sha256.New()
+	raw := io.TeeReader(r, hash)
+	dr, err := Decompress(raw)
+	if err != nil {
+		return 0, err
+	}
+	defer dr.Close()
+
+	return s.bulkImport(opts, func(b *bulkImporter) error {
+		scanner := bufio.NewScanner(dr)
+		for scanner.Scan() {
+			if err := b.add(scanner.Text()); err != nil {
+				return err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		// hash whatever the decompressor left unread
+		if _, err := io.Copy(io.Discard, raw); err != nil {
+			return err
+		}
+		b.checksum = hex.EncodeToString(hash.Sum(nil))
+		return nil
+	})
+}
+
+// The number of rows sent to the staging tables per insert statement
+const stageChunkSize = 250
+
+// The statements creating the staging tables of an import, on the connection of the import only
+var stageTablesStmts = []string{
+	`drop table if exists temp.import_words`,
+	`drop table if exists temp.import_word_tags`,
+	`drop table if exists temp.import_tags`,
+	`drop table if exists temp.import_ids`,
+	`create temp table import_words(name text not null)`,
+	`create temp table import_word_tags(name text not null, tag text not null)`,
+	`create temp table import_tags(id integer not null primary key)`,
+	`create temp table import_ids(source_id integer, batch_id integer not null, now integer not null)`,
+}
+
+// The statements moving a batch of staged lines over to the words and wt tables
+var importBatchStmts = []string{
+	`insert or ignore into words(name,batch_id,created_at,updated_at) select i.name,ids.batch_id,ids.now,ids.now from temp.import_words as i cross join temp.import_ids as ids order by i.rowid`,
+	`insert or ignore into wt(word_id,tag_id,batch_id,created_at) select w.id,t.id,ids.batch_id,ids.now from temp.import_words as i join words as w on w.name=i.name cross join temp.import_tags as t cross join temp.import_ids as ids`,
+	`insert or ignore into tags(name,batch_id) select i.tag,ids.batch_id from temp.import_word_tags as i cross join temp.import_ids as ids order by i.rowid`,
+	`insert or ignore into wt(word_id,tag_id,batch_id,created_at) select w.id,t.id,ids.batch_id,ids.now from temp.import_word_tags as i join words as w on w.name=i.name join tags as t on t.name=i.tag cross join temp.import_ids as ids`,
+	`update words set updated_at=ids.now from temp.import_words as i cross join temp.import_ids as ids where words.name=i.name and words.updated_at<ids.now and exists (select 1 from wt where wt.word_id=words.id and +wt.batch_id=ids.batch_id and wt.created_at=ids.now)`,
+	`insert or ignore into word_sources(word_id,source_id) select w.id,ids.source_id from temp.import_words as i join words as w on w.name=i.name cross join temp.import_ids as ids where ids.source_id is not null`,
+	`delete from temp.import_words`,
+	`delete from temp.import_word_tags`,
+}
+
+// bulkImporter stages the imported lines in temporary tables and moves them over to
+// the words and wt tables with set based statements, one transaction per batch.
+// Only the rows of a single insert statement are held in memory.
+type bulkImporter struct {
+	ctx context.Context
+	// the temporary tables only exist on the connection that created them
+	conn *sql.Conn
+	tx   *sql.Tx
+	// the multi rows inserts of the staging tables for full chunks, prepared per transaction
+	wordsStmt    *sql.Stmt
+	wordTagsStmt *sql.Stmt
+	// the delimiter of the inline tags, empty for the plain format
+	delimiter string
+	// the source of the lines, when recorded, and the sha256 of its content once known
+	sourceID  int64
+	checksum  string
+	batchSize int
+	// the non blank lines read so far, and the ones staged in the current transaction
+	lines  int64
+	staged int
+	// the pending rows of the staging tables
+	words    []interface{}
+	wordTags []interface{}
+}
+
+// Run feed with an importer configured by opts, committing the lines it adds.
+// It returns the number of non blank lines added.
+func (s *Store) bulkImport(opts ImportOptions, feed func(b *bulkImporter) error) (int64, error) {
+	b := &bulkImporter{ctx: context.Background(), batchSize: opts.BatchSize}
+	switch opts.Format {
+	case "", FormatPlain:
+	case FormatTagged:
+		b.delimiter = opts.Delimiter
+		if b.delimiter == "" {
+			b.delimiter = DefaultDelimiter
+		}
+	default:
+		return 0, fmt.Errorf("unknown import format %q, valid formats are %s, %s", opts.Format, FormatPlain, FormatTagged)
+	}
+	if b.batchSize <= 0 {
+		b.batchSize = DefaultBatchSize
+	}
+
+	var err error
+	if b.conn, err = s.db.Conn(b.ctx); err != nil {
+		return 0, err
+	}
+	defer b.close()
+	if err = b.prepare(opts); err != nil {
+		return 0, err
+	}
+	if err = feed(b); err != nil {
+		return b.lines, err
+	}
+	if err = b.commit(); err != nil {
+		return b.lines, err
+	}
+	if b.sourceID != 0 && b.checksum != "" {
+		_, err = b.conn.ExecContext(b.ctx, "update sources set sha256=? where id=?", b.checksum, b.sourceID)
+	}
+	return b.lines, err
+}
+
+// Create the staging tables, stage the ids of the tags given to every word and record the source and batch
+func (b *bulkImporter) prepare(opts ImportOptions) error {
+	for _, query := range stageTablesStmts {
+		if _, err := b.conn.ExecContext(b.ctx, query); err != nil {
+			return err
+		}
+	}
+
+	tx, err := b.conn.BeginTx(b.ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	batchID := opts.BatchID
+	if batchID == 0 {
+		if batchID, err = newBatch(tx, opts.Source); err != nil {
+			return err
+		}
+	}
+	tagIDs, err := ensureTags(tx, opts.Tags, batchID)
+	if err != nil {
+		return err
+	}
+	for _, id := range tagIDs {
+		if _, err = tx.Exec("insert or ignore into temp.import_tags(id) values(?)", id); err != nil {
+			return err
+		}
+	}
+	if opts.Source != "" {
+		if b.sourceID, err = addSource(tx, opts.Source, opts.Tags, batchID); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.Exec("insert into temp.import_ids(source_id,batch_id,now) values(nullif(?,0),?,?)", b.sourceID, batchID, time.Now().Unix()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Build a multi rows insert into a staging table
+func stageInsert(table string, columns int, rows int) string {
+	row := "(?" + strings.Repeat(",?", columns-1) + ")"
+	return "insert into temp." + table + " values " + row + strings.Repeat(","+row, rows-1)
+}
+
+// Stage a line, committing the current batch once it is full
+func (b *bulkImporter) add(line string) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	b.lines++
+
+	word, tags := strings.TrimSpace(line), []string(nil)
+	if b.delimiter != "" {
+		word, tags = ParseTaggedLine(line, b.delimiter)
+	}
+	if word == "" {
+		return nil
+	}
+	b.words = append(b.words, word)
+	for _, tag := range tags {
+		b.wordTags = append(b.wordTags, word, tag)
+	}
+	if len(b.words) >= stageChunkSize || len(b.wordTags) >= 2*stageChunkSize {
+		if err := b.stage(); err != nil {
+			return err
+		}
+	}
+	if b.staged++; b.staged >= b.batchSize {
+		return b.commit()
+	}
+	return nil
+}
+
+// Send the pending rows to the staging tables
+func (b *bulkImporter) stage() error {
+	if b.tx == nil {
+		var err error
+		if b.tx, err = b.conn.BeginTx(b.ctx, nil); err != nil {
+			return err
+		}
+		if b.wordsStmt, err = b.tx.Prepare(stageInsert("import_words", 1, stageChunkSize)); err != nil {
+			return err
+		}
+		if b.wordTagsStmt, err = b.tx.Prepare(stageInsert("import_word_tags", 2, stageChunkSize)); err != nil {
+			return err
+		}
+	}
+	if err := b.stageRows(b.wordsStmt, "import_words", 1, b.words); err != nil {
+		return err
+	}
+	if err := b.stageRows(b.wordTagsStmt, "import_word_tags", 2, b.wordTags); err != nil {
+		return err
+	}
+	b.words, b.wordTags = b.words[:0], b.wordTags[:0]
+	return nil
+}
+
+func (b *bulkImporter) stageRows(chunkStmt *sql.Stmt, table string, columns int, args []interface{}) error {
+	for len(args) > 0 {
+		var err error
+		if n := len(args) / columns; n >= stageChunkSize {
+			_, err = chunkStmt.Exec(args[:stageChunkSize*columns]...)
+			args = args[stageChunkSize*columns:]
+		} else {
+			_, err = b.tx.ExecContext(b.ctx, stageInsert(table, columns, n), args...)
+			args = nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Move the staged lines over to the words and wt tables and commit them
+func (b *bulkImporter) commit() error {
+	if err := b.stage(); err != nil {
+		return err
+	}
+	for _, query := range importBatchStmts {
+		if _, err := b.tx.ExecContext(b.ctx, query); err != nil {
+			return err
+		}
+	}
+	err := b.tx.Commit()
+	b.tx, b.staged = nil, 0
+	if err != nil {
+		return err
+	}
+	log.Infoln("Lines:", b.lines)
+	return nil
+}
+
+// Roll back the uncommitted lines and release the connection along with its staging tables
+func (b *bulkImporter) close() {
+	if b.tx != nil {
+		b.tx.Rollback()
+	}
+	for _, table := range []string{"import_words", "import_word_tags", "import_tags", "import_ids"} {
+		b.conn.ExecContext(b.ctx, "drop table if exists temp."+table)
+	}
+	b.conn.Close()
+}
@@ -0,0 +1,162 @@
+package store
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportReader(t *testing.T) {
+	s := newTestStore(t)
+
+	const batchSize = 1000
+	var lines []string
+	for i := 0; i < batchSize*2+10; i++ {
+		lines = append(lines, "word"+strings.Repeat("x", i%7)+string(rune('a'+i%26))+strings.Repeat("y", i/26))
+	}
+	lines = append(lines, "", "  ", lines[0])
+
+	count, err := s.ImportReader(strings.NewReader(strings.Join(lines, "\n")), ImportOptions{Tags: []string{"a", "b"}, BatchSize: batchSize})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(batchSize*2+11), count)
+	assert.Equal(t, int64(batchSize*2+10), countRows(t, s, "words"))
+	assert.Equal(t, int64((batchSize*2+10)*2), countRows(t, s, "wt"))
+
+	// words are inserted in the order of the file
+	first, err := s.WordID(lines[0])
+	assert.NoError(t, err)
+	last, err := s.WordID(lines[batchSize*2+9])
+	assert.NoError(t, err)
+	assert.Equal(t, int64(batchSize*2+9), last-first)
+
+	count, err = s.ImportReader(strings.NewReader(""), ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestImportReaderExistingWords(t *testing.T) {
+	s := newTestStore(t)
+
+	assert.NoError(t, s.AddWords([]string{"admin", "login"}, []string{"old"}))
+	count, err := s.ImportReader(strings.NewReader("login\nadmin\nnew\n"), ImportOptions{Tags: []string{"new"}, BatchSize: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	assert.Equal(t, int64(3), countRows(t, s, "words"))
+
+	info, err := s.Word("admin")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"new", "old"}, info.Tags)
+	info, err = s.Word("new")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"new"}, info.Tags)
+}
+
+func TestImportReaderTagged(t *testing.T) {
+	s := newTestStore(t)
+
+	input := "admin\tphp,drupal\nlogin\t php , ,php\nbare\n\tnoword\nwith\ttab\tapi\n"
+	count, err := s.ImportReader(strings.NewReader(input), ImportOptions{Tags: []string{"all"}, Format: FormatTagged})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+
+	tests := map[string][]string{
+		"admin":     {"all", "drupal", "php"},
+		"login":     {"all", "php"},
+		"bare":      {"all"},
+		"with\ttab": {"all", "api"},
+	}
+	for word, tags := range tests {
+		info, err := s.Word(word)
+		if assert.NoErrorf(t, err, `word %q`, word) {
+			assert.Equalf(t, tags, info.Tags, `word %q`, word)
+		}
+	}
+	assert.Equal(t, int64(4), countRows(t, s, "words"))
+
+	count, err = s.ImportReader(strings.NewReader("x|a,b\ny|b"), ImportOptions{Format: FormatTagged, Delimiter: "|"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	tag, err := s.Tag("b")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), tag.Words)
+
+	_, err = s.ImportReader(strings.NewReader("x"), ImportOptions{Format: "xml"})
+	assert.EqualError(t, err, `unknown import format "xml", valid formats are plain, tagged`)
+}
+
+func TestParseTaggedLine(t *testing.T) {
+	word, tags := ParseTaggedLine("admin\tphp, drupal,php", "\t")
+	assert.Equal(t, "admin", word)
+	assert.Equal(t, []string{"php", "drupal"}, tags)
+
+	word, tags = ParseTaggedLine(" plain word ", "\t")
+	assert.Equal(t, "plain word", word)
+	assert.Nil(t, tags)
+
+	word, tags = ParseTaggedLine("a;b;c", ";")
+	assert.Equal(t, "a;b", word)
+	assert.Equal(t, []string{"c"}, tags)
+}
+
+func TestFormatTaggedLine(t *testing.T) {
+	assert.Equal(t, "admin\tphp,drupal", FormatTaggedLine("admin", []string{"php", "drupal"}, "\t"))
+	assert.Equal(t, "admin", FormatTaggedLine("admin", nil, "\t"))
+
+	word, tags := ParseTaggedLine(FormatTaggedLine("two words", []string{"a", "b"}, DefaultDelimiter), DefaultDelimiter)
+	assert.Equal(t, "two words", word)
+	assert.Equal(t, []string{"a", "b"}, tags)
+}
+
+// lineGenerator reads n distinct generated words, one per line
+type lineGenerator struct {
+	n, i int
+	buf  []byte
+}
+
+func (g *lineGenerator) Read(p []byte) (int, error) {
+	for len(g.buf) < len(p) && g.i < g.n {
+		g.buf = strconv.AppendInt(append(g.buf, "word-"...), int64(g.i), 36)
+		g.buf = append(g.buf, '\n')
+		g.i++
+	}
+	if len(g.buf) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, g.buf)
+	g.buf = g.buf[:copy(g.buf, g.buf[n:])]
+	return n, nil
+}
+
+// Run with go test -run XXX -bench ImportReader -benchtime 1x ./store, the 10M lines import takes a couple of minutes
+func BenchmarkImportReader(b *testing.B) {
+	sizes := []struct {
+		name  string
+		lines int
+	}{{"100k", 100000}, {"1M", 1000000}, {"10M", 10000000}}
+	for _, size := range sizes {
+		lines := size.lines
+		b.Run(size.name, func(b *testing.B) {
+			var elapsed time.Duration
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				s := newTestStore(b)
+				b.StartTimer()
+
+				start := time.Now()
+				count, err := s.ImportReader(&lineGenerator{n: lines}, ImportOptions{Tags: []string{"bench", "generated"}})
+				elapsed += time.Since(start)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if count != int64(lines) {
+					b.Fatalf("imported %d lines instead of %d", count, lines)
+				}
+			}
+			b.ReportMetric(float64(lines*b.N)/elapsed.Seconds(), "lines/s")
+		})
+	}
+}
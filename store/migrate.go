@@ -0,0 +1,283 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrSchemaTooNew is returned when a database was created by a newer version of orunmila
+	ErrSchemaTooNew = errors.New("database schema is newer than this version of orunmila understands")
+	// ErrSchemaOutdated is returned when a database opened for reading needs to be upgraded first
+	ErrSchemaOutdated = errors.New("database schema is outdated, run orunmila vacuum to upgrade it")
+)
+
+// migration upgrades the schema from version-1 to version
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// The schema migrations, in order. Never edit a released migration, append a new one instead.
+var migrations = []migration{
+	{1, "initial schema", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+		create table IF NOT EXISTS words (id integer not null primary key AUTOINCREMENT, name text NOT NULL UNIQUE);
+		create table IF NOT EXISTS tags (id integer not null primary key AUTOINCREMENT, name text NOT NULL UNIQUE);
+		create table IF NOT EXISTS wt (word_id integer not null , tag_id integer not null, FOREIGN KEY(word_id) REFERENCES words(id),FOREIGN KEY(tag_id) REFERENCES tags(id),PRIMARY KEY(word_id,tag_id));
+		create table IF NOT EXISTS sysconfig(name text not null primary key, val text);
+		insert or ignore into sysconfig(name,val) values ("dbname","default");
+		`)
+		return err
+	}},
+	{2, "index wt by tag", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`create index IF NOT EXISTS wt_tag_id on wt(tag_id)`)
+		return err
+	}},
+	{3, "tag descriptions", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`alter table tags add column description text not null default ''`)
+		return err
+	}},
+	{4, "word sources", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+		create table sources (id integer not null primary key AUTOINCREMENT, path text not null, sha256 text not null default '', imported_at integer not null, tags text not null default '');
+		create table word_sources (word_id integer not null, source_id integer not null, FOREIGN KEY(word_id) REFERENCES words(id), FOREIGN KEY(source_id) REFERENCES sources(id), PRIMARY KEY(word_id,source_id));
+		create index word_sources_source_id on word_sources(source_id);
+		`)
+		return err
+	}},
+	{5, "import batches", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+		create table batches (id integer not null primary key AUTOINCREMENT, command text not null default '', created_at integer not null);
+		alter table words add column batch_id integer REFERENCES batches(id);
+		alter table tags add column batch_id integer REFERENCES batches(id);
+		alter table wt add column batch_id integer REFERENCES batches(id);
+		alter table sources add column batch_id integer REFERENCES batches(id);
+		create index words_batch_id on words(batch_id);
+		create index wt_batch_id on wt(batch_id);
+		create index sources_batch_id on sources(batch_id);
+		`)
+		return err
+	}},
+	{6, "timestamps", func(tx *sql.Tx) error {
+		// existing rows get the time of their batch when known, the time of the upgrade otherwise
+		_, err := tx.Exec(`
+		alter table words add column created_at integer not null default 0;
+		alter table words add column updated_at integer not null default 0;
+		alter table wt add column created_at integer not null default 0;
+		update wt set created_at=ifnull((select created_at from batches where id=wt.batch_id),cast(strftime('%s','now') as integer));
+		update words set created_at=ifnull((select created_at from batches where id=words.batch_id),cast(strftime('%s','now') as integer));
+		update words set updated_at=max(created_at,ifnull((select max(created_at) from wt where word_id=words.id),0));
+		create index words_created_at on words(created_at);
+		create trigger wt_delete_updates_word after delete on wt begin
+			update words set updated_at=cast(strftime('%s','now') as integer) where id=old.word_id;
+		end;
+		`)
+		return err
+	}},
+	{7, "search checkpoints", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+		create table checkpoints (id integer not null primary key AUTOINCREMENT, name text not null unique, created_at integer not null, updated_at integer not null);
+		create table checkpoint_words (checkpoint_id integer not null, word_id integer not null, FOREIGN KEY(checkpoint_id) REFERENCES checkpoints(id), FOREIGN KEY(word_id) REFERENCES words(id), PRIMARY KEY(checkpoint_id,word_id));
+		create index checkpoint_words_word_id on checkpoint_words(word_id);
+		create trigger words_delete_checkpoint_words after delete on words begin
+			delete from checkpoint_words where word_id=old.id;
+		end;
+		`)
+		return err
+	}},
+	{8, "extension sets", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+		create table ext_sets (id integer not null primary key AUTOINCREMENT, name text not null unique, extensions text not null default '');
+		create table tag_ext_sets (tag_id integer not null, ext_set_id integer not null, FOREIGN KEY(tag_id) REFERENCES tags(id), FOREIGN KEY(ext_set_id) REFERENCES ext_sets(id), PRIMARY KEY(tag_id,ext_set_id));
+		create index tag_ext_sets_ext_set_id on tag_ext_sets(ext_set_id);
+		create trigger tags_delete_tag_ext_sets after delete on tags begin
+			delete from tag_ext_sets where tag_id=old.id;
+		end;
+		`)
+		return err
+	}},
+	{9, "scores", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+		alter table words add column score real not null default 0;
+		alter table wt add column score real not null default 0;
+		`)
+		return err
+	}},
+	{10, "hits", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+		alter table words add column hits integer not null default 0;
+		alter table wt add column hits integer not null default 0;
+		`)
+		return err
+	}},
+	{11, "technology tags", func(tx *sql.Tx) error {
+		// the initial mapping, frozen here as DefaultTechTags may change in later versions
+		_, err := tx.Exec(`
+		create table tech_tags (pattern text not null, tag text not null, PRIMARY KEY(pattern,tag));
+		insert into tech_tags(pattern,tag) values
+			('nginx','nginx'),
+			('openresty','nginx'),
+			('apache','apache'),
+			('apache http server','apache'),
+			('apache tomcat','tomcat'),('apache tomcat','java'),('apache tomcat','jsp'),
+			('tomcat','tomcat'),('tomcat','java'),('tomcat','jsp'),
+			('microsoft iis','iis'),('microsoft iis','asp'),('microsoft iis','windows'),
+			('iis','iis'),('iis','asp'),('iis','windows'),
+			('microsoft asp.net','aspnet'),('microsoft asp.net','asp'),('microsoft asp.net','windows'),
+			('asp.net','aspnet'),('asp.net','asp'),('asp.net','windows'),
+			('*sharepoint*','sharepoint'),('*sharepoint*','aspnet'),
+			('litespeed','litespeed'),
+			('caddy','caddy'),
+			('php','php'),
+			('drupal','drupal'),('drupal','php'),
+			('wordpress','wordpress'),('wordpress','php'),
+			('joomla','joomla'),('joomla','php'),
+			('magento','magento'),('magento','php'),
+			('laravel','laravel'),('laravel','php'),
+			('symfony','symfony'),('symfony','php'),
+			('codeigniter','codeigniter'),('codeigniter','php'),
+			('python','python'),
+			('django','django'),('django','python'),
+			('flask','flask'),('flask','python'),
+			('ruby','ruby'),
+			('ruby on rails','rails'),('ruby on rails','ruby'),
+			('node.js','nodejs'),
+			('express','express'),('express','nodejs'),
+			('next.js','nextjs'),('next.js','nodejs'),
+			('java','java'),
+			('jboss*','jboss'),('jboss*','java'),
+			('wildfly','jboss'),('wildfly','java'),
+			('spring*','spring'),('spring*','java'),
+			('*coldfusion','coldfusion'),
+			('jenkins','jenkins'),('jenkins','java'),
+			('grafana','grafana'),
+			('kibana','kibana'),
+			('elasticsearch','elasticsearch'),
+			('graphql','graphql'),('graphql','api'),
+			('swagger ui','swagger'),('swagger ui','api');
+		`)
+		return err
+	}},
+	{12, "batch hits", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+		create table batch_hits (batch_id integer not null, word_id integer not null, tag_id integer not null default 0, n integer not null, FOREIGN KEY(batch_id) REFERENCES batches(id), FOREIGN KEY(word_id) REFERENCES words(id), PRIMARY KEY(batch_id,word_id,tag_id));
+		create index batch_hits_word_id on batch_hits(word_id);
+		create trigger words_delete_batch_hits after delete on words begin
+			delete from batch_hits where word_id=old.id;
+		end;
+		create trigger tags_delete_batch_hits after delete on tags begin
+			delete from batch_hits where tag_id=old.id;
+		end;
+		`)
+		return err
+	}},
+}
+
+// SchemaVersion is the latest schema version known to this package
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// Reads the schema version recorded in sysconfig.
+// Databases created before versioning was introduced report version 0.
+func schemaVersion(q queryer) (int, error) {
+	var n int
+	err := q.QueryRow("select count(*) from sqlite_master where type='table' and name='sysconfig'").Scan(&n)
+	if err != nil || n == 0 {
+		return 0, err
+	}
+
+	var val string
+	err = q.QueryRow("select ifnull(val,'') from sysconfig where name='version'").Scan(&val)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if val == "" || val == "0.0.0" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema version %q", val)
+	}
+	return version, nil
+}
+
+// SchemaVersion returns the schema version of the database
+func (s *Store) SchemaVersion() (int, error) {
+	return schemaVersion(s.db)
+}
+
+// Ensure the database can be read by this version, without modifying it
+func checkSchema(db *sql.DB) error {
+	version, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > SchemaVersion() {
+		return fmt.Errorf("%w (database %d, supported %d)", ErrSchemaTooNew, version, SchemaVersion())
+	}
+	if version < SchemaVersion() {
+		return fmt.Errorf("%w (database %d, current %d)", ErrSchemaOutdated, version, SchemaVersion())
+	}
+	return nil
+}
+
+// Migrate applies any pending schema migrations, each one in its own transaction.
+// It returns the schema versions before and after the upgrade.
+func (s *Store) Migrate() (from int, to int, err error) {
+	from, err = schemaVersion(s.db)
+	if err != nil {
+		return from, from, err
+	}
+	if from > SchemaVersion() {
+		return from, from, fmt.Errorf("%w (database %d, supported %d)", ErrSchemaTooNew, from, SchemaVersion())
+	}
+
+	to = from
+	for _, m := range migrations {
+		if m.version <= to {
+			continue
+		}
+		if err = applyMigration(s.db, m); err != nil {
+			return from, to, fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		log.Debugf("[Migrate] applied migration %d (%s)", m.version, m.name)
+		to = m.version
+	}
+	return from, to, nil
+}
+
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// another process may have upgraded the database in the meantime
+	current, err := schemaVersion(tx)
+	if err != nil {
+		return err
+	}
+	if current >= m.version {
+		return nil
+	}
+
+	if err = m.up(tx); err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT OR REPLACE INTO sysconfig(name,val) values ('version',?)", strconv.Itoa(m.version))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
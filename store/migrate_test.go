@@ -0,0 +1,108 @@
+package store
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Create a database file out of a fixture from testdata
+func loadFixture(t *testing.T, name string) string {
+	t.Helper()
+	script, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fixture.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err = db.Exec(string(script)); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSchemaVersionOfNewDatabase(t *testing.T) {
+	s := newTestStore(t)
+	version, err := s.SchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersion(), version)
+}
+
+func TestMigrateFixtures(t *testing.T) {
+	for _, fixture := range []string{"schema-v0.sql", "schema-nosysconfig.sql"} {
+		path := loadFixture(t, fixture)
+
+		_, err := OpenReadOnly(path)
+		assert.ErrorIsf(t, err, ErrSchemaOutdated, `%s: OpenReadOnly should refuse outdated databases`, fixture)
+
+		s, err := Open(path)
+		if !assert.NoErrorf(t, err, `%s: Open failed to upgrade`, fixture) {
+			continue
+		}
+		version, err := s.SchemaVersion()
+		assert.NoError(t, err)
+		assert.Equalf(t, SchemaVersion(), version, `%s: schema version after upgrade`, fixture)
+
+		expr, _ := ParseQuery("php and not drupal")
+		assert.Equalf(t, []Record{{Word: "login", Tags: []string{"php"}, ID: 2}}, search(t, s, SearchOptions{Query: expr}), `%s: data lost in upgrade`, fixture)
+		assert.Equal(t, int64(3), countRows(t, s, "words"))
+		var untimed int64
+		assert.NoError(t, s.DB().QueryRow("select count(*) from words where created_at=0 or updated_at=0").Scan(&untimed))
+		assert.Equalf(t, int64(0), untimed, `%s: timestamps not backfilled`, fixture)
+
+		// nothing left to do the second time around
+		from, to, err := s.Migrate()
+		assert.NoError(t, err)
+		assert.Equal(t, from, to)
+		s.Close()
+
+		s, err = OpenReadOnly(path)
+		assert.NoErrorf(t, err, `%s: OpenReadOnly after upgrade`, fixture)
+		s.Close()
+	}
+}
+
+func TestMigrateKeepsSettings(t *testing.T) {
+	s, err := Open(loadFixture(t, "schema-v0.sql"))
+	assert.NoError(t, err)
+	defer s.Close()
+
+	settings, err := s.Info()
+	assert.NoError(t, err)
+	assert.Contains(t, settings, Setting{Name: "description", Value: "fixture v0"})
+	assert.Contains(t, settings, Setting{Name: "dbname", Value: "default"})
+}
+
+func TestRefuseNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "newer.db")
+	assert.NoError(t, Create(path))
+	s, err := Open(path)
+	assert.NoError(t, err)
+	_, err = s.DB().Exec("update sysconfig set val=? where name='version'", SchemaVersion()+1)
+	assert.NoError(t, err)
+
+	_, _, err = s.Migrate()
+	assert.ErrorIs(t, err, ErrSchemaTooNew)
+	s.Close()
+
+	_, err = Open(path)
+	assert.ErrorIs(t, err, ErrSchemaTooNew)
+	_, err = OpenReadOnly(path)
+	assert.ErrorIs(t, err, ErrSchemaTooNew)
+}
+
+func TestInvalidSchemaVersion(t *testing.T) {
+	s := newTestStore(t)
+	_, err := s.DB().Exec("update sysconfig set val='garbage' where name='version'")
+	assert.NoError(t, err)
+
+	_, err = s.SchemaVersion()
+	assert.EqualError(t, err, `invalid schema version "garbage"`)
+}
@@ -0,0 +1,362 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Expr is a node of a parsed tag query
+type Expr interface {
+	// toSQL renders the node as a condition over the given words.id column,
+	// appending any placeholder values to args
+	toSQL(col string, args *[]interface{}) string
+	String() string
+}
+
+// tagTerm matches the words associated with a single tag
+type tagTerm struct {
+	name string
+}
+
+// notExpr matches the words not matched by its operand
+type notExpr struct {
+	expr Expr
+}
+
+// andExpr matches the words matched by both operands
+type andExpr struct {
+	left, right Expr
+}
+
+// orExpr matches the words matched by either operand
+type orExpr struct {
+	left, right Expr
+}
+
+func (t tagTerm) toSQL(col string, args *[]interface{}) string {
+	*args = append(*args, t.name)
+	return col + ` IN (SELECT wt.word_id FROM wt JOIN tags ON tags.id=wt.tag_id WHERE tags.name=?)`
+}
+
+func (n notExpr) toSQL(col string, args *[]interface{}) string {
+	return "NOT (" + n.expr.toSQL(col, args) + ")"
+}
+
+func (a andExpr) toSQL(col string, args *[]interface{}) string {
+	return "(" + a.left.toSQL(col, args) + " AND " + a.right.toSQL(col, args) + ")"
+}
+
+func (o orExpr) toSQL(col string, args *[]interface{}) string {
+	return "(" + o.left.toSQL(col, args) + " OR " + o.right.toSQL(col, args) + ")"
+}
+
+func (t tagTerm) String() string {
+	for _, r := range t.name {
+		if isOperatorRune(r) || unicode.IsSpace(r) {
+			return fmt.Sprintf("%q", t.name)
+		}
+	}
+	switch strings.ToLower(t.name) {
+	case "and", "or", "not":
+		return fmt.Sprintf("%q", t.name)
+	}
+	return t.name
+}
+
+func (n notExpr) String() string { return "not " + n.expr.String() }
+func (a andExpr) String() string { return "(" + a.left.String() + " and " + a.right.String() + ")" }
+func (o orExpr) String() string  { return "(" + o.left.String() + " or " + o.right.String() + ")" }
+
+// Tag returns a query matching the words associated with the given tag
+func Tag(name string) Expr { return tagTerm{name} }
+
+// Not returns a query matching the words not matched by expr
+func Not(expr Expr) Expr { return notExpr{expr} }
+
+// And returns a query matching the words matched by both left and right
+func And(left, right Expr) Expr { return andExpr{left, right} }
+
+// Or returns a query matching the words matched by either left or right
+func Or(left, right Expr) Expr { return orExpr{left, right} }
+
+// TagNames collects the unique tag names referenced by a query, in order of appearance
+func TagNames(expr Expr) []string {
+	var names []string
+	seen := make(map[string]bool)
+	var walk func(e Expr)
+	walk = func(e Expr) {
+		switch n := e.(type) {
+		case tagTerm:
+			if !seen[n.name] {
+				seen[n.name] = true
+				names = append(names, n.name)
+			}
+		case notExpr:
+			walk(n.expr)
+		case andExpr:
+			walk(n.left)
+			walk(n.right)
+		case orExpr:
+			walk(n.left)
+			walk(n.right)
+		}
+	}
+	if expr != nil {
+		walk(expr)
+	}
+	return names
+}
+
+// IncludedTagNames returns the unique tag names of a query that words may match,
+// leaving out the ones under an odd number of not
+func IncludedTagNames(expr Expr) []string {
+	var names []string
+	seen := make(map[string]bool)
+	var walk func(e Expr, negated bool)
+	walk = func(e Expr, negated bool) {
+		switch n := e.(type) {
+		case tagTerm:
+			if !negated && !seen[n.name] {
+				seen[n.name] = true
+				names = append(names, n.name)
+			}
+		case notExpr:
+			walk(n.expr, !negated)
+		case andExpr:
+			walk(n.left, negated)
+			walk(n.right, negated)
+		case orExpr:
+			walk(n.left, negated)
+			walk(n.right, negated)
+		}
+	}
+	if expr != nil {
+		walk(expr, false)
+	}
+	return names
+}
+
+// TagsQuery builds an OR query out of a list of tags.
+// Returns nil when the list holds no tags.
+func TagsQuery(tags []string) Expr {
+	var expr Expr
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		if expr == nil {
+			expr = tagTerm{tag}
+		} else {
+			expr = orExpr{expr, tagTerm{tag}}
+		}
+	}
+	return expr
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokTag
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func isOperatorRune(r rune) bool {
+	return strings.ContainsRune(`()&|!,"`, r)
+}
+
+// Split a tag query into tokens
+func lexQuery(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case r == '!':
+			tokens = append(tokens, token{tokNot, "!", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokOr, ",", i})
+			i++
+		case r == '&' || r == '|':
+			start := i
+			for i < len(runes) && runes[i] == r {
+				i++
+			}
+			if i-start > 2 {
+				return nil, fmt.Errorf("unexpected %q at position %d", string(runes[start:i]), start)
+			}
+			kind := tokAnd
+			if r == '|' {
+				kind = tokOr
+			}
+			tokens = append(tokens, token{kind, string(runes[start:i]), start})
+		case r == '"':
+			// a quoted tag, where \" and \\ stand for a quote and a backslash
+			start := i
+			var name strings.Builder
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				if runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+					i++
+				}
+				name.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated quote at position %d", start)
+			}
+			if name.Len() == 0 {
+				return nil, fmt.Errorf("empty tag at position %d", start)
+			}
+			tokens = append(tokens, token{tokTag, name.String(), start})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !isOperatorRune(runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+			kind := tokTag
+			switch strings.ToLower(word) {
+			case "and":
+				kind = tokAnd
+			case "or":
+				kind = tokOr
+			case "not":
+				kind = tokNot
+			}
+			tokens = append(tokens, token{kind, word, start})
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(runes)}), nil
+}
+
+// queryParser is a recursive descent parser for tag queries.
+//
+//	expr    := and { ("or" | "|" | "||" | ",") and }
+//	and     := unary { ("and" | "&" | "&&") unary }
+//	unary   := ("not" | "!") unary | primary
+//	primary := "(" expr ")" | tag
+type queryParser struct {
+	tokens []token
+	pos    int
+}
+
+// ParseQuery parses a boolean tag query such as `(drupal or wordpress) and php and not windows`
+func ParseQuery(input string) (Expr, error) {
+	tokens, err := lexQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, fmt.Errorf("empty tag query")
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return expr, nil
+}
+
+func (p *queryParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (Expr, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (Expr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokTag:
+		return tagTerm{tok.text}, nil
+	case tokLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, fmt.Errorf("missing closing parenthesis for the one at position %d", tok.pos)
+		}
+		return expr, nil
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := map[string]string{
+		"php":                      "php",
+		"a or b and c":             "(a or (b and c))",
+		"(a or b) and c":           "((a or b) and c)",
+		"not a and b":              "(not a and b)",
+		"!(a | b) && c":            "(not (a or b) and c)",
+		"a,b,c":                    "((a or b) or c)",
+		"a-b AND NOT c.d":          "(a-b and not c.d)",
+		`"and" or "two words"`:     `("and" or "two words")`,
+		`"say \"hi\"" or "a\\b"`:   `("say \"hi\"" or a\b)`,
+		`"c:\dir" or "c:\\my dir"`: `(c:\dir or "c:\\my dir")`,
+		"(drupal or wordpress) and php and not windows": "(((drupal or wordpress) and php) and not windows)",
+	}
+	for input, wants := range tests {
+		expr, err := ParseQuery(input)
+		if assert.NoErrorf(t, err, `ParseQuery(%q)`, input) {
+			assert.Equalf(t, wants, expr.String(), `ParseQuery(%q)`, input)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := map[string]string{
+		"":          "empty tag query",
+		"a and":     "unexpected end of query",
+		"(a or b":   "missing closing parenthesis for the one at position 0",
+		"a b":       `unexpected "b" at position 2`,
+		"a ||| b":   `unexpected "|||" at position 2`,
+		`"a`:        "unterminated quote at position 0",
+		`"a\"`:      "unterminated quote at position 0",
+		`a or ""`:   "empty tag at position 5",
+		"a and ) b": `unexpected ")" at position 6`,
+	}
+	for input, wants := range tests {
+		_, err := ParseQuery(input)
+		assert.EqualErrorf(t, err, wants, `ParseQuery(%q)`, input)
+	}
+}
+
+func TestTagsQuery(t *testing.T) {
+	assert.Nil(t, TagsQuery(nil))
+	assert.Nil(t, TagsQuery([]string{" ", ""}))
+	assert.Equal(t, "((a or b) or c)", TagsQuery([]string{"a", " b", "c", "a"}).String())
+
+	// the queries of any tag names parse back to the same names
+	names := []string{`say "hi"`, `a"b`, `c:\my dir`, "not"}
+	expr, err := ParseQuery(TagsQuery(names).String())
+	if assert.NoError(t, err) {
+		assert.Equal(t, names, TagNames(expr))
+	}
+}
+
+func TestTagNames(t *testing.T) {
+	expr, err := ParseQuery("(a or b) and not (c or a)")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, TagNames(expr))
+	assert.Nil(t, TagNames(nil))
+}
+
+func TestIncludedTagNames(t *testing.T) {
+	expr, err := ParseQuery("(a or b) and not (c or a) and not not d")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "d"}, IncludedTagNames(expr))
+	assert.Nil(t, IncludedTagNames(nil))
+}
+
+func TestQueryToSQL(t *testing.T) {
+	db := newTestStore(t).DB()
+
+	_, err := db.Exec(`insert into words(id,name) values (1,'one'),(2,'two'),(3,'three'),(4,'four');
+	insert into tags(id,name) values (1,'a'),(2,'b'),(3,'c');
+	insert into wt(word_id,tag_id) values (1,1),(2,1),(2,2),(3,2),(3,3);`)
+	assert.NoError(t, err)
+
+	tests := map[string][]string{
+		"a":               {"one", "two"},
+		"a and b":         {"two"},
+		"a or c":          {"one", "three", "two"},
+		"b and not c":     {"two"},
+		"not (a or b)":    {"four"},
+		"missing or c":    {"three"},
+		"not missing":     {"four", "one", "three", "two"},
+		"(a or c) and !b": {"one"},
+	}
+	for input, wants := range tests {
+		expr, err := ParseQuery(input)
+		assert.NoError(t, err)
+		var args []interface{}
+		rows, err := db.Query("select name from words as t1 where "+expr.toSQL("t1.id", &args), args...)
+		if !assert.NoErrorf(t, err, `query %q`, input) {
+			continue
+		}
+		var got []string
+		for rows.Next() {
+			var name string
+			assert.NoError(t, rows.Scan(&name))
+			got = append(got, name)
+		}
+		rows.Close()
+		sort.Strings(got)
+		assert.Equalf(t, wants, got, `query %q`, input)
+	}
+}
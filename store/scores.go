@@ -0,0 +1,107 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The orders of the words returned by Search
+const (
+	// SortID returns the words in the order they were inserted, the default
+	SortID = ""
+	// SortScore returns the words with the highest score first
+	SortScore = "score"
+	// SortAlpha returns the words in byte order
+	SortAlpha = "alpha"
+	// SortLength returns the shortest words first
+	SortLength = "length"
+	// SortAdded returns the oldest words first
+	SortAdded = "added"
+)
+
+// SortOrders lists the orders accepted by SearchOptions.Sort on top of the default one
+var SortOrders = []string{SortScore, SortAlpha, SortLength, SortAdded}
+
+// Compile the number of distinct sources of the word of the id column col. The imports of a file with
+// the same sha256, and the add runs, count as a single source however many times they are repeated
+func sourceCountSQL(col string) string {
+	return `(select count(distinct coalesce(nullif(s.sha256,''),s.path)) from word_sources as ws join sources as s on s.id=ws.source_id where ws.word_id=` + col + `)`
+}
+
+// Compile the score of the words of t1: their own score and hits plus the number of their sources, plus
+// with a query the scores and hits of their associations with the tags the query includes
+func scoreSQL(query Expr, args *[]interface{}) string {
+	score := `t1.score+t1.hits+` + sourceCountSQL("t1.id")
+	names := IncludedTagNames(query)
+	if len(names) == 0 {
+		return "(" + score + ")"
+	}
+	for _, name := range names {
+		*args = append(*args, name)
+	}
+	return "(" + score + `+ifnull((select sum(wt.score+wt.hits) from wt join tags on tags.id=wt.tag_id where wt.word_id=t1.id and tags.name in (?` + strings.Repeat(",?", len(names)-1) + `)),0))`
+}
+
+// Compile the order by clause of a sort order, ties are broken by the word ids.
+// The score is expected as the ranking column of the results.
+func orderSQL(sort string) (string, error) {
+	switch sort {
+	case SortID:
+		return ` ORDER BY t1.id`, nil
+	case SortScore:
+		return ` ORDER BY ranking DESC, t1.id`, nil
+	case SortAlpha:
+		return ` ORDER BY t1.name, t1.id`, nil
+	case SortLength:
+		return ` ORDER BY length(t1.name), t1.name, t1.id`, nil
+	case SortAdded:
+		return ` ORDER BY t1.created_at, t1.id`, nil
+	}
+	return "", fmt.Errorf("unknown sort order %q, valid orders are %s", sort, strings.Join(SortOrders, ", "))
+}
+
+// ScoreWords sets the score of words, or the score of their associations with tags when some are given.
+// It returns the number of words or associations updated.
+func (s *Store) ScoreWords(words []string, tags []string, score float64) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := "update words set score=? where name=?"
+	if len(tags) > 0 {
+		query = "update wt set score=? where word_id=(select id from words where name=?) and tag_id=(select id from tags where name=?)"
+	}
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var updated int64
+	exec := func(args ...interface{}) error {
+		result, err := stmt.Exec(args...)
+		if err != nil {
+			return err
+		}
+		n, _ := result.RowsAffected()
+		updated += n
+		return nil
+	}
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if len(tags) == 0 {
+			if err = exec(score, word); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		for _, tag := range tags {
+			if err = exec(score, word, tag); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return updated, tx.Commit()
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchSort(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"zeta", "alpha", "mid"}, []string{"php"}))
+	// alpha gets a second source
+	_, err := s.ImportReader(strings.NewReader("alpha\n"), ImportOptions{Tags: []string{"api"}, Source: "list.txt"})
+	assert.NoError(t, err)
+	_, err = s.DB().Exec("update words set created_at=100 where name='mid'")
+	assert.NoError(t, err)
+
+	n, err := s.ScoreWords([]string{"mid", "missing"}, nil, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	n, err = s.ScoreWords([]string{"zeta"}, []string{"php", "api"}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	php, _ := ParseQuery("php")
+	words := func(opts SearchOptions) []string {
+		var names []string
+		for _, rec := range search(t, s, opts) {
+			names = append(names, rec.Word)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"zeta", "alpha", "mid"}, words(SearchOptions{}))
+	assert.Equal(t, []string{"alpha", "mid", "zeta"}, words(SearchOptions{Sort: SortAlpha}))
+	assert.Equal(t, []string{"mid", "zeta", "alpha"}, words(SearchOptions{Sort: SortLength}))
+	assert.Equal(t, []string{"mid", "zeta", "alpha"}, words(SearchOptions{Sort: SortAdded}))
+	// mid 5+1, alpha 0+2 and zeta 0+1, plus 3 for its association with php
+	assert.Equal(t, []string{"mid", "alpha", "zeta"}, words(SearchOptions{Sort: SortScore}))
+	assert.Equal(t, []string{"mid", "zeta", "alpha"}, words(SearchOptions{Query: php, Sort: SortScore}))
+	assert.Equal(t, []string{"mid", "zeta"}, words(SearchOptions{Query: php, Sort: SortScore, Limit: 2}))
+	assert.Equal(t, []string{"zeta"}, words(SearchOptions{Limit: 1}))
+
+	records := search(t, s, SearchOptions{Query: php, Sort: SortScore})
+	assert.Equal(t, []float64{6, 4, 2}, []float64{records[0].Score, records[1].Score, records[2].Score})
+
+	count, err := s.Count(SearchOptions{Sort: SortScore, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// a limited checkpoint only records the words it returned
+	assert.Equal(t, []string{"mid", "alpha"}, words(SearchOptions{Sort: SortScore, Limit: 2, Checkpoint: "top"}))
+	assert.Equal(t, []string{"zeta"}, words(SearchOptions{Sort: SortScore, Limit: 2, Checkpoint: "top"}))
+
+	err = s.Search(SearchOptions{Sort: "random"}, func(Record) error { return nil })
+	assert.EqualError(t, err, `unknown sort order "random", valid orders are score, alpha, length, added`)
+
+	info, err := s.Word("zeta")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), info.Score)
+	assert.Equal(t, map[string]float64{"php": 3}, info.TagScores)
+}
+
+func TestScoreDistinctSources(t *testing.T) {
+	s := newTestStore(t)
+	score := func() float64 {
+		info, err := s.Word("admin")
+		assert.NoError(t, err)
+		return info.Score
+	}
+
+	// the same file imported twice, from two paths, is a single source
+	for _, path := range []string{"list.txt", "copy/list.txt"} {
+		_, err := s.ImportReader(strings.NewReader("admin\nlogin\n"), ImportOptions{Source: path})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, float64(1), score())
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, s.AddWords([]string{"admin"}, nil))
+	}
+	assert.Equal(t, float64(2), score(), "the add runs are one more source")
+
+	_, err := s.ImportReader(strings.NewReader("admin\n"), ImportOptions{Source: "other.txt"})
+	assert.NoError(t, err)
+	assert.Equal(t, float64(3), score())
+
+	records := search(t, s, SearchOptions{Sort: SortScore})
+	assert.Equal(t, "admin", records[0].Word)
+	assert.Equal(t, float64(3), records[0].Score)
+}
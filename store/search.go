@@ -0,0 +1,210 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Record is a single search result
+type Record struct {
+	Word string   `json:"word"`
+	Tags []string `json:"tags"`
+	ID   int64    `json:"id"`
+	// Score is only set when sorting by score
+	Score float64 `json:"score,omitempty"`
+}
+
+// SearchOptions narrow down the words returned by Search
+type SearchOptions struct {
+	// Query selects the words by their tags, nil matches every word
+	Query Expr
+	// Sources keeps the words imported from one of the given sources, matched against
+	// their path, as a glob pattern, or their sha256
+	Sources []string
+	// Since and Until, when not zero, keep the words added within [Since, Until).
+	// With a query, they apply to the associations with the tags of the query rather than the words.
+	Since time.Time
+	Until time.Time
+	// Checkpoint, when set, skips the words already returned under that name and records
+	// the returned ones, creating the checkpoint if needed. It needs a writable store.
+	Checkpoint string
+	// Sort is one of SortOrders, the words are returned in insertion order when empty
+	Sort string
+	// Limit, when positive, is the maximum number of words returned
+	Limit int
+}
+
+// Search calls fn for every word matching the options, stopping at the first error
+func (s *Store) Search(opts SearchOptions, fn func(Record) error) error {
+	q, err := s.searchFrom(opts)
+	if err != nil {
+		return err
+	}
+	if opts.Checkpoint == "" {
+		return scanRecords(s.db, q, fn)
+	}
+
+	// the words are recorded in the same transaction, once all of them went through fn
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	id, err := ensureCheckpoint(tx, opts.Checkpoint)
+	if err != nil {
+		return err
+	}
+	if err = scanRecords(tx, q, fn); err != nil {
+		return err
+	}
+	query, args := q.selectSQL("t1.id")
+	result, err := tx.Exec(`insert or ignore into checkpoint_words(checkpoint_id,word_id) select ?,id from (`+query+`)`, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return err
+	}
+	n, _ := result.RowsAffected()
+	log.Debugf("[Search] recorded %d words in checkpoint %s", n, opts.Checkpoint)
+	return tx.Commit()
+}
+
+// Peek calls fn for every word matching the options, as Search does, without recording them in a checkpoint
+func (s *Store) Peek(opts SearchOptions, fn func(Record) error) error {
+	q, err := s.searchFrom(opts)
+	if err != nil {
+		return err
+	}
+	return scanRecords(s.db, q, fn)
+}
+
+// Count returns the number of words matching the options, without recording them in a checkpoint
+func (s *Store) Count(opts SearchOptions) (int64, error) {
+	q, err := s.searchFrom(opts)
+	if err != nil {
+		return 0, err
+	}
+	query, args := q.selectSQL("t1.id")
+	var count int64
+	err = s.db.QueryRow(`select count(*) from (`+query+`)`, args...).Scan(&count)
+	return count, err
+}
+
+// searchQuery selects the words matching the options of a search along with their ranking
+type searchQuery struct {
+	// the ranking expression and its arguments, 0 unless sorting by score
+	ranking     string
+	rankingArgs []interface{}
+	// the from, where, order by and limit clauses and their arguments
+	from string
+	args []interface{}
+}
+
+// Build the select of the given columns of t1, followed by the ranking of the words
+func (q searchQuery) selectSQL(cols string) (string, []interface{}) {
+	args := append(append([]interface{}{}, q.rankingArgs...), q.args...)
+	return `select ` + cols + `,` + q.ranking + ` as ranking ` + q.from, args
+}
+
+// Build the query selecting the words matching the options
+func (s *Store) searchFrom(opts SearchOptions) (searchQuery, error) {
+	q := searchQuery{ranking: "0", from: `from words as t1`}
+	var conds []string
+
+	order, err := orderSQL(opts.Sort)
+	if err != nil {
+		return q, err
+	}
+	if opts.Query != nil {
+		names := TagNames(opts.Query)
+		missing, err := s.MissingTags(names)
+		if err != nil {
+			return q, err
+		}
+		if len(missing) == len(names) {
+			return q, fmt.Errorf("none of the tags in %q exist in the db", opts.Query.String())
+		}
+		conds = append(conds, opts.Query.toSQL("t1.id", &q.args))
+	}
+	if len(opts.Sources) > 0 {
+		conds = append(conds, sourcesSQL("t1.id", opts.Sources, &q.args))
+	}
+	if !opts.Since.IsZero() || !opts.Until.IsZero() {
+		conds = append(conds, timeSQL(opts, &q.args))
+	}
+	if opts.Checkpoint != "" {
+		conds = append(conds, checkpointSQL("t1.id", opts.Checkpoint, &q.args))
+	}
+	if len(conds) > 0 {
+		q.from += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	if opts.Sort == SortScore {
+		q.ranking = scoreSQL(opts.Query, &q.rankingArgs)
+	}
+	q.from += order
+	if opts.Limit > 0 {
+		q.from += ` LIMIT ?`
+		q.args = append(q.args, opts.Limit)
+	}
+	return q, nil
+}
+
+// Run the search query and call fn for every record
+func scanRecords(db interface {
+	Query(string, ...interface{}) (*sql.Rows, error)
+}, q searchQuery, fn func(Record) error) error {
+	queryStr, args := q.selectSQL(`t1.id,t1.name,ifnull((select group_concat(name,',') from (select name from tags where id in (select tag_id from wt where word_id=t1.id) order by name)),'') as tagged`)
+	log.Debugln("[Search] query:", queryStr, args)
+
+	rows, err := db.Query(queryStr, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rec Record
+		var tags string
+		if err = rows.Scan(&rec.ID, &rec.Word, &tags, &rec.Score); err != nil {
+			return err
+		}
+		rec.Tags = []string{}
+		if tags != "" {
+			rec.Tags = strings.Split(tags, ",")
+		}
+		if err = fn(rec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Compile the time range of the options, over the associations with the tags the
+// query includes when there are some, over the creation time of the words otherwise
+func timeSQL(opts SearchOptions, args *[]interface{}) string {
+	names := IncludedTagNames(opts.Query)
+	col := "t1.created_at"
+	if len(names) > 0 {
+		col = "wt.created_at"
+	}
+	var conds []string
+	if !opts.Since.IsZero() {
+		conds = append(conds, col+" >= ?")
+		*args = append(*args, opts.Since.Unix())
+	}
+	if !opts.Until.IsZero() {
+		conds = append(conds, col+" < ?")
+		*args = append(*args, opts.Until.Unix())
+	}
+	if len(names) == 0 {
+		return strings.Join(conds, " AND ")
+	}
+
+	for _, name := range names {
+		*args = append(*args, name)
+	}
+	return "EXISTS (SELECT 1 FROM wt JOIN tags ON tags.id=wt.tag_id WHERE wt.word_id=t1.id AND " + strings.Join(conds, " AND ") +
+		" AND tags.name IN (?" + strings.Repeat(",?", len(names)-1) + "))"
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// collect the search results
+func search(t *testing.T, s *Store, opts SearchOptions) []Record {
+	t.Helper()
+	var records []Record
+	err := s.Search(opts, func(rec Record) error {
+		records = append(records, rec)
+		return nil
+	})
+	assert.NoError(t, err)
+	return records
+}
+
+func TestSearch(t *testing.T) {
+	s := newTestStore(t)
+
+	err := s.Search(SearchOptions{Query: TagsQuery([]string{"a", "b"})}, nil)
+	assert.EqualError(t, err, `none of the tags in "(a or b)" exist in the db`)
+
+	assert.NoError(t, s.AddWords([]string{"untagged"}, nil))
+	assert.NoError(t, s.AddWords([]string{"one"}, []string{"a"}))
+	assert.NoError(t, s.AddWords([]string{"two"}, []string{"a", "b"}))
+
+	assert.Equal(t, []Record{
+		{Word: "untagged", Tags: []string{}, ID: 1},
+		{Word: "one", Tags: []string{"a"}, ID: 2},
+		{Word: "two", Tags: []string{"a", "b"}, ID: 3},
+	}, search(t, s, SearchOptions{}))
+
+	expr, err := ParseQuery("a and not b")
+	assert.NoError(t, err)
+	assert.Equal(t, []Record{{Word: "one", Tags: []string{"a"}, ID: 2}}, search(t, s, SearchOptions{Query: expr}))
+
+	stop := errors.New("stop")
+	err = s.Search(SearchOptions{}, func(Record) error { return stop })
+	assert.Equal(t, stop, err)
+}
+
+func TestCount(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"one", "two"}, []string{"a"}))
+	assert.NoError(t, s.AddWords([]string{"three"}, []string{"b"}))
+
+	count, err := s.Count(SearchOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	expr, _ := ParseQuery("a")
+	count, err = s.Count(SearchOptions{Query: expr, Checkpoint: "unused"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, int64(0), countRows(t, s, "checkpoints"))
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// AddSource is the source recorded for the words added with AddWords
+const AddSource = "<add>"
+
+// SourceInfo describes a file, URL or command words were imported from
+type SourceInfo struct {
+	ID         int64     `json:"id"`
+	Path       string    `json:"path"`
+	SHA256     string    `json:"sha256"`
+	ImportedAt time.Time `json:"imported_at"`
+	Tags       []string  `json:"tags"`
+}
+
+// Record a new import of path within a batch, along with the tags given to its words
+func addSource(tx *sql.Tx, path string, tags []string, batchID int64) (int64, error) {
+	result, err := tx.Exec("insert into sources(path,imported_at,tags,batch_id) values(?,?,?,?)", path, time.Now().Unix(), strings.Join(tags, ","), batchID)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// Lists the sources of a word, oldest first
+func wordSources(q *sql.DB, wordID int64) ([]SourceInfo, error) {
+	rows, err := q.Query("select s.id,s.path,s.sha256,s.imported_at,s.tags from sources as s join word_sources as ws on ws.source_id=s.id where ws.word_id=? order by s.imported_at,s.id", wordID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := []SourceInfo{}
+	for rows.Next() {
+		var src SourceInfo
+		var importedAt int64
+		var tags string
+		if err = rows.Scan(&src.ID, &src.Path, &src.SHA256, &importedAt, &tags); err != nil {
+			return nil, err
+		}
+		src.ImportedAt = time.Unix(importedAt, 0)
+		src.Tags = []string{}
+		if tags != "" {
+			src.Tags = strings.Split(tags, ",")
+		}
+		sources = append(sources, src)
+	}
+	return sources, rows.Err()
+}
+
+// Compile a filter keeping the words imported from one of the given sources,
+// matched against their path, as a glob pattern, or their sha256
+func sourcesSQL(col string, sources []string, args *[]interface{}) string {
+	var conds []string
+	for _, source := range sources {
+		conds = append(conds, "s.path GLOB ? OR s.sha256 = ?")
+		*args = append(*args, source, strings.ToLower(source))
+	}
+	return col + " IN (SELECT ws.word_id FROM word_sources AS ws JOIN sources AS s ON s.id=ws.source_id WHERE " + strings.Join(conds, " OR ") + ")"
+}
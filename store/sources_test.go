@@ -0,0 +1,65 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportReaderSources(t *testing.T) {
+	s := newTestStore(t)
+
+	content := "admin\nlogin\n"
+	_, err := s.ImportReader(strings.NewReader(content), ImportOptions{Tags: []string{"b", "a"}, Source: "lists/raft.txt"})
+	assert.NoError(t, err)
+	_, err = s.ImportReader(strings.NewReader("login\nbackup\n"), ImportOptions{Source: "https://example.com/bad.txt"})
+	assert.NoError(t, err)
+	_, err = s.ImportReader(strings.NewReader("untracked\n"), ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), countRows(t, s, "sources"))
+
+	sum := sha256.Sum256([]byte(content))
+	checksum := hex.EncodeToString(sum[:])
+	info, err := s.Word("login")
+	assert.NoError(t, err)
+	if assert.Len(t, info.Sources, 2) {
+		assert.Equal(t, "lists/raft.txt", info.Sources[0].Path)
+		assert.Equal(t, checksum, info.Sources[0].SHA256)
+		assert.Equal(t, []string{"b", "a"}, info.Sources[0].Tags)
+		assert.False(t, info.Sources[0].ImportedAt.IsZero())
+		assert.Equal(t, "https://example.com/bad.txt", info.Sources[1].Path)
+		assert.Equal(t, []string{}, info.Sources[1].Tags)
+	}
+	info, err = s.Word("untracked")
+	assert.NoError(t, err)
+	assert.Empty(t, info.Sources)
+
+	words := func(opts SearchOptions) []string {
+		var names []string
+		for _, rec := range search(t, s, opts) {
+			names = append(names, rec.Word)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"login", "backup"}, words(SearchOptions{Sources: []string{"https://example.com/bad.txt"}}))
+	assert.Equal(t, []string{"admin", "login"}, words(SearchOptions{Sources: []string{"lists/*"}}))
+	assert.Equal(t, []string{"admin", "login"}, words(SearchOptions{Sources: []string{strings.ToUpper(checksum)}}))
+	assert.Equal(t, []string{"admin", "login", "backup"}, words(SearchOptions{Sources: []string{"lists/*", "*bad*"}}))
+	assert.Empty(t, words(SearchOptions{Sources: []string{"missing.txt"}}))
+
+	expr, _ := ParseQuery("a")
+	assert.Equal(t, []string{"admin", "login"}, words(SearchOptions{Query: expr, Sources: []string{"lists/*", "*bad*"}}))
+}
+
+func TestRemoveWordsSources(t *testing.T) {
+	s := newTestStore(t)
+	_, err := s.ImportReader(strings.NewReader("admin\nlogin\n"), ImportOptions{Source: "raft.txt"})
+	assert.NoError(t, err)
+
+	_, err = s.RemoveWords([]string{"admin"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), countRows(t, s, "word_sources"))
+}
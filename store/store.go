@@ -0,0 +1,114 @@
+// Package store manages orunmila word list databases.
+//
+// A database holds words, tags and the associations between them, a Store
+// wraps the database handle and exposes the operations the orunmila
+// subcommands are built upon.
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var (
+	// ErrNotFound is returned when a tag or word does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrExists is returned when a tag or word already exists
+	ErrExists = errors.New("already exists")
+)
+
+// Store is an orunmila word list database
+type Store struct {
+	db *sql.DB
+}
+
+// Create creates the database file if needed and brings its schema up to date
+func Create(path string) error {
+	s, err := open(fmt.Sprintf("file:%s?mode=rwc", path))
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	_, _, err = s.Migrate()
+	return err
+}
+
+// Open opens an existing database for reading and writing, applying any pending schema migrations
+func Open(path string) (*Store, error) {
+	return openAndMigrate(fmt.Sprintf("file:%s?mode=rw", path))
+}
+
+// OpenShared opens an existing database for long running processes serving concurrent readers and writers,
+// applying any pending schema migrations. The database is switched to write-ahead logging, so that the readers
+// do not block the writer, the transactions take the write lock upfront and wait for it rather than failing.
+func OpenShared(path string) (*Store, error) {
+	return openAndMigrate(fmt.Sprintf("file:%s?mode=rw&_journal_mode=WAL&_busy_timeout=%d&_txlock=immediate", path, sharedBusyTimeout))
+}
+
+// The number of milliseconds the statements of a shared database wait for a lock
+const sharedBusyTimeout = 10000
+
+// OpenReadOnly opens an existing database for reading only.
+// It fails with ErrSchemaOutdated when the database has pending schema migrations.
+func OpenReadOnly(path string) (*Store, error) {
+	s, err := open(fmt.Sprintf("file:%s?mode=ro", path))
+	if err != nil {
+		return nil, err
+	}
+	if err = checkSchema(s.db); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Open the database of dsn and apply any pending schema migrations
+func openAndMigrate(dsn string) (*Store, error) {
+	s, err := open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := s.Migrate()
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	if from != to {
+		log.Infof("[store] upgraded database schema from version %d to %d", from, to)
+	}
+	return s, nil
+}
+
+func open(dsn string) (*Store, error) {
+	log.Debugln("[store] using dsn:", dsn)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// New wraps an already opened database handle
+func New(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// DB returns the underlying database handle
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+// Close closes the underlying database handle
+func (s *Store) Close() error {
+	return s.db.Close()
+}
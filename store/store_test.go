@@ -0,0 +1,103 @@
+package store
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	log.SetOutput(io.Discard)
+}
+
+// Create a new database in a temporary directory and open it
+func newTestStore(t testing.TB) *Store {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	if err := Create(path); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestCreate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "random.db")
+
+	err := Create(path)
+	assert.NoError(t, err, `Create failed`)
+
+	err = Create(path)
+	assert.NoError(t, err, `2nd Create failed`)
+
+	_, err = os.Stat(path)
+	assert.NoError(t, err)
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "random.db")
+
+	_, err := Open(path)
+	assert.Error(t, err, `Open should fail on missing databases`)
+
+	assert.NoError(t, Create(path))
+	s, err := Open(path)
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddWords([]string{"word"}, nil))
+	assert.NoError(t, s.Close())
+
+	s, err = OpenReadOnly(path)
+	assert.NoError(t, err)
+	defer s.Close()
+	assert.Error(t, s.AddWords([]string{"other"}, nil), `read only databases should not be writable`)
+}
+
+func TestOpenShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.db")
+
+	_, err := OpenShared(path)
+	assert.Error(t, err, `OpenShared should fail on missing databases`)
+
+	assert.NoError(t, Create(path))
+	s, err := OpenShared(path)
+	assert.NoError(t, err)
+	defer s.Close()
+
+	var mode string
+	assert.NoError(t, s.db.QueryRow("pragma journal_mode").Scan(&mode))
+	assert.Equal(t, "wal", mode)
+
+	// concurrent writers wait for each other while the readers go on
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			errs <- s.AddWords([]string{fmt.Sprintf("word%d", i)}, []string{"shared"})
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, err := s.Count(SearchOptions{})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	count, err := s.Count(SearchOptions{Query: Tag("shared")})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), count)
+}
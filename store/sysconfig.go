@@ -0,0 +1,42 @@
+package store
+
+import (
+	"strings"
+)
+
+// Setting is a database system configuration entry
+type Setting struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Describe sets the database description
+func (s *Store) Describe(desc string) error {
+	_, err := s.db.Exec("INSERT OR REPLACE INTO sysconfig(name,val) values (?,?)", "description", strings.TrimSpace(desc))
+	return err
+}
+
+// Info returns the database system configuration
+func (s *Store) Info() ([]Setting, error) {
+	rows, err := s.db.Query("SELECT name,ifnull(val,'') FROM sysconfig")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var settings []Setting
+	for rows.Next() {
+		var setting Setting
+		if err = rows.Scan(&setting.Name, &setting.Value); err != nil {
+			return nil, err
+		}
+		settings = append(settings, setting)
+	}
+	return settings, rows.Err()
+}
+
+// Vacuum rebuilds the database file, repacking it into a minimal amount of disk space
+func (s *Store) Vacuum() error {
+	_, err := s.db.Exec("VACUUM")
+	return err
+}
@@ -0,0 +1,22 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeAndInfo(t *testing.T) {
+	s := newTestStore(t)
+
+	assert.NoError(t, s.Describe("  My Description for this database "))
+	settings, err := s.Info()
+	assert.NoError(t, err)
+	assert.Contains(t, settings, Setting{Name: "description", Value: "My Description for this database"})
+	assert.Contains(t, settings, Setting{Name: "dbname", Value: "default"})
+}
+
+func TestVacuum(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.Vacuum())
+}
@@ -0,0 +1,292 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Gets the ID of a given tag, or -1 when the tag does not exist
+func tagID(q queryer, tag string) (int64, error) {
+	var id int64
+	err := q.QueryRow("select id from tags where name = ?", tag).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return -1, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+// TagID returns the ID of a given tag, or -1 when the tag does not exist
+func (s *Store) TagID(tag string) (int64, error) {
+	return tagID(s.db, tag)
+}
+
+// MissingTags returns the given tags that do not exist in the database
+func (s *Store) MissingTags(tags []string) ([]string, error) {
+	var missing []string
+	for _, tag := range tags {
+		id, err := tagID(s.db, tag)
+		if err != nil {
+			return nil, err
+		}
+		if id <= 0 {
+			missing = append(missing, tag)
+		}
+	}
+	return missing, nil
+}
+
+// Insert the missing tags, as introduced by the given batch unless zero, and return the ids of all of them, keyed by name
+func ensureTags(tx *sql.Tx, tags []string, batchID int64) (map[string]int64, error) {
+	ids := make(map[string]int64)
+	for _, tag := range tags {
+		if _, ok := ids[tag]; ok || tag == "" {
+			continue
+		}
+		id, err := tagID(tx, tag)
+		if err != nil {
+			return nil, err
+		}
+		if id <= 0 {
+			result, err := tx.Exec("insert into tags(name,batch_id) values(?,nullif(?,0))", tag, batchID)
+			if err != nil {
+				return nil, err
+			}
+			if id, err = result.LastInsertId(); err != nil {
+				return nil, err
+			}
+		}
+		log.Debugf("[ensureTags] tag %s => id: %d", tag, id)
+		ids[tag] = id
+	}
+	return ids, nil
+}
+
+// TagInfo describes a tag along with the number of words associated with it
+type TagInfo struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Words       int64  `json:"words"`
+}
+
+const tagInfoQuery = `select t.id,t.name,t.description,(select count(*) from wt where wt.tag_id=t.id) from tags as t`
+
+func scanTagInfo(row interface{ Scan(...interface{}) error }) (TagInfo, error) {
+	var info TagInfo
+	err := row.Scan(&info.ID, &info.Name, &info.Description, &info.Words)
+	return info, err
+}
+
+// Tags lists every tag along with its word count, ordered by name
+func (s *Store) Tags() ([]TagInfo, error) {
+	rows, err := s.db.Query(tagInfoQuery + ` order by t.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []TagInfo
+	for rows.Next() {
+		info, err := scanTagInfo(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, info)
+	}
+	return tags, rows.Err()
+}
+
+// Tag returns the details of a single tag
+func (s *Store) Tag(name string) (TagInfo, error) {
+	info, err := scanTagInfo(s.db.QueryRow(tagInfoQuery+` where t.name=?`, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return info, fmt.Errorf("tag %q: %w", name, ErrNotFound)
+	}
+	return info, err
+}
+
+// Gets the ID of an existing tag, failing with ErrNotFound when it does not exist
+func existingTagID(q queryer, tag string) (int64, error) {
+	id, err := tagID(q, tag)
+	if err == nil && id <= 0 {
+		err = fmt.Errorf("tag %q: %w", tag, ErrNotFound)
+	}
+	return id, err
+}
+
+// RenameTag renames a tag, failing with ErrExists when the new name is taken
+func (s *Store) RenameTag(oldName, newName string) error {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return fmt.Errorf("the new tag name can not be empty")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	id, err := existingTagID(tx, oldName)
+	if err != nil {
+		return err
+	}
+	if other, err := tagID(tx, newName); err != nil {
+		return err
+	} else if other > 0 {
+		return fmt.Errorf("tag %q: %w, merge the tags instead", newName, ErrExists)
+	}
+	if _, err = tx.Exec("update tags set name=? where id=?", newName, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MergeTags moves the words and extension sets of the source tags over to dest and deletes the source tags.
+// The dest tag is created when it does not exist. It returns the number of associations added to dest.
+func (s *Store) MergeTags(sources []string, dest string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	destIDs, err := ensureTags(tx, []string{dest}, 0)
+	if err != nil {
+		return 0, err
+	}
+	destID := destIDs[dest]
+
+	var added int64
+	for _, source := range sources {
+		if source == dest {
+			continue
+		}
+		sourceID, err := existingTagID(tx, source)
+		if err != nil {
+			return 0, err
+		}
+		var n int64
+		if err = tx.QueryRow("select count(*) from wt where tag_id=? and word_id not in (select word_id from wt where tag_id=?)", sourceID, destID).Scan(&n); err != nil {
+			return 0, err
+		}
+		added += n
+		// the scores and hits of the words already associated with dest add up, and the association
+		// keeps the earliest of the batches, so that undoing the later one does not remove it
+		if _, err = tx.Exec("insert into wt(word_id,tag_id,batch_id,created_at,score,hits) select word_id,?,batch_id,created_at,score,hits from wt where tag_id=? on conflict(word_id,tag_id) do update set score=score+excluded.score, hits=hits+excluded.hits, batch_id=min(batch_id,excluded.batch_id), created_at=min(created_at,excluded.created_at)", destID, sourceID); err != nil {
+			return 0, err
+		}
+		if _, err = tx.Exec("insert or ignore into tag_ext_sets(tag_id,ext_set_id) select ?,ext_set_id from tag_ext_sets where tag_id=?", destID, sourceID); err != nil {
+			return 0, err
+		}
+		// the hits recorded by the feedback batches follow the associations, so that the batches can still be undone
+		if _, err = tx.Exec("insert into batch_hits(batch_id,word_id,tag_id,n) select batch_id,word_id,?,n from batch_hits where tag_id=? on conflict(batch_id,word_id,tag_id) do update set n=n+excluded.n", destID, sourceID); err != nil {
+			return 0, err
+		}
+		if err = deleteTag(tx, sourceID); err != nil {
+			return 0, err
+		}
+		log.Debugf("[MergeTags] merged %s into %s, %d new associations", source, dest, n)
+	}
+	return added, tx.Commit()
+}
+
+// DeleteTag deletes a tag along with its word associations.
+// It returns the number of associations removed.
+func (s *Store) DeleteTag(name string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := existingTagID(tx, name)
+	if err != nil {
+		return 0, err
+	}
+	var links int64
+	if err = tx.QueryRow("select count(*) from wt where tag_id=?", id).Scan(&links); err != nil {
+		return 0, err
+	}
+	if err = deleteTag(tx, id); err != nil {
+		return 0, err
+	}
+	return links, tx.Commit()
+}
+
+// Removes a tag and its associations
+func deleteTag(tx *sql.Tx, id int64) error {
+	if _, err := tx.Exec("delete from wt where tag_id=?", id); err != nil {
+		return err
+	}
+	_, err := tx.Exec("delete from tags where id=?", id)
+	return err
+}
+
+// DescribeTag sets the description of an existing tag
+func (s *Store) DescribeTag(name, desc string) error {
+	result, err := s.db.Exec("update tags set description=? where name=?", strings.TrimSpace(desc), name)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("tag %q: %w", name, ErrNotFound)
+	}
+	return nil
+}
+
+// TagOverlapInfo counts the words a pair of tags have in common
+type TagOverlapInfo struct {
+	Tag   string `json:"tag"`
+	Other string `json:"other"`
+	Words int64  `json:"words"`
+}
+
+// TagOverlap counts the words shared by every pair of the given tags, the pairs without any are left out.
+// The pairs are ordered by decreasing number of words, it fails with ErrNotFound when a tag does not exist.
+func (s *Store) TagOverlap(tags []string) ([]TagOverlapInfo, error) {
+	var ids []interface{}
+	for _, tag := range tags {
+		id, err := existingTagID(s.db, tag)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	overlaps := []TagOverlapInfo{}
+	if len(ids) < 2 {
+		return overlaps, nil
+	}
+
+	in := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	rows, err := s.db.Query(`select t1.name,t2.name,count(*) as words from wt as a
+		join wt as b on b.word_id=a.word_id and b.tag_id>a.tag_id
+		join tags as t1 on t1.id=a.tag_id
+		join tags as t2 on t2.id=b.tag_id
+		where a.tag_id in (`+in+`) and b.tag_id in (`+in+`)
+		group by a.tag_id,b.tag_id order by words desc,t1.name,t2.name`, append(ids, ids...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var o TagOverlapInfo
+		if err = rows.Scan(&o.Tag, &o.Other, &o.Words); err != nil {
+			return nil, err
+		}
+		overlaps = append(overlaps, o)
+	}
+	return overlaps, rows.Err()
+}
@@ -0,0 +1,202 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnsureTags(t *testing.T) {
+	s := newTestStore(t)
+
+	tx, err := s.DB().Begin()
+	assert.NoError(t, err)
+	ids, err := ensureTags(tx, []string{"a", "b", "c", "a", ""}, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+	assert.Len(t, ids, 3)
+	for k, v := range ids {
+		assert.Greaterf(t, v, int64(0), `ensureTags: failed initial insert for key %v has id %v`, k, v)
+	}
+
+	tx, err = s.DB().Begin()
+	assert.NoError(t, err)
+	again, err := ensureTags(tx, []string{"a", "b", "c", "d"}, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+	for k, v := range ids {
+		assert.Equalf(t, v, again[k], `ensureTags: existing tag %v changed id`, k)
+	}
+	assert.Greater(t, again["d"], int64(0), `ensureTags: failed to insert new tag d`)
+}
+
+func TestTagID(t *testing.T) {
+	s := newTestStore(t)
+
+	id, err := s.TagID("a")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), id)
+
+	assert.NoError(t, s.AddWords([]string{"word"}, []string{"a"}))
+	id, err = s.TagID("a")
+	assert.NoError(t, err)
+	assert.Greater(t, id, int64(0))
+}
+
+func TestMissingTags(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"word"}, []string{"a", "c"}))
+
+	missing, err := s.MissingTags([]string{"a", "b", "c", "d"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "d"}, missing)
+}
+
+func TestTags(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"one", "two"}, []string{"b", "a"}))
+	assert.NoError(t, s.AddWords([]string{"three"}, []string{"b"}))
+
+	tags, err := s.Tags()
+	assert.NoError(t, err)
+	if assert.Len(t, tags, 2) {
+		assert.Equal(t, "a", tags[0].Name)
+		assert.Equal(t, int64(2), tags[0].Words)
+		assert.Equal(t, "b", tags[1].Name)
+		assert.Equal(t, int64(3), tags[1].Words)
+	}
+
+	assert.NoError(t, s.DescribeTag("a", " the a tag "))
+	tag, err := s.Tag("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "the a tag", tag.Description)
+
+	_, err = s.Tag("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, s.DescribeTag("missing", "nope"), ErrNotFound)
+}
+
+func TestTagOverlap(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"one", "two", "three"}, []string{"a", "b"}))
+	assert.NoError(t, s.AddWords([]string{"three", "four"}, []string{"c"}))
+	assert.NoError(t, s.AddWords([]string{"five"}, []string{"d"}))
+
+	overlaps, err := s.TagOverlap([]string{"c", "b", "a", "d"})
+	assert.NoError(t, err)
+	assert.Equal(t, []TagOverlapInfo{{"a", "b", 3}, {"a", "c", 1}, {"b", "c", 1}}, overlaps)
+
+	overlaps, err = s.TagOverlap([]string{"a"})
+	assert.NoError(t, err)
+	assert.Empty(t, overlaps)
+
+	_, err = s.TagOverlap([]string{"a", "missing"})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRenameTag(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"one"}, []string{"nodjs", "node"}))
+
+	assert.ErrorIs(t, s.RenameTag("missing", "other"), ErrNotFound)
+	assert.ErrorIs(t, s.RenameTag("nodjs", "node"), ErrExists)
+	assert.Error(t, s.RenameTag("nodjs", " "))
+
+	assert.NoError(t, s.RenameTag("nodjs", "nodejs"))
+	missing, err := s.MissingTags([]string{"nodjs", "nodejs"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nodjs"}, missing)
+	assert.Equal(t, int64(2), countRows(t, s, "wt"))
+}
+
+func TestMergeTags(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"both", "nodejs-only"}, []string{"nodejs"}))
+	assert.NoError(t, s.AddWords([]string{"both", "node-only"}, []string{"node"}))
+	assert.NoError(t, s.AddWords([]string{"js-only"}, []string{"js"}))
+
+	added, err := s.MergeTags([]string{"nodejs", "js", "node"}, "node")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), added)
+
+	tags, err := s.Tags()
+	assert.NoError(t, err)
+	assert.Equal(t, []TagInfo{{ID: 2, Name: "node", Words: 4}}, tags)
+	assert.Equal(t, int64(4), countRows(t, s, "wt"))
+
+	_, err = s.MergeTags([]string{"missing"}, "node")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// merging into a new tag creates it
+	_, err = s.MergeTags([]string{"node"}, "nodejs")
+	assert.NoError(t, err)
+	tag, err := s.Tag("nodejs")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), tag.Words)
+}
+
+func TestMergeTagsScores(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"both", "nodejs-only"}, []string{"nodejs"}))
+	assert.NoError(t, s.AddWords([]string{"both"}, []string{"node"}))
+	_, err := s.ScoreWords([]string{"both", "nodejs-only"}, []string{"nodejs"}, 10)
+	assert.NoError(t, err)
+	_, err = s.ScoreWords([]string{"both"}, []string{"node"}, 2)
+	assert.NoError(t, err)
+	_, err = s.DB().Exec("update wt set hits=3 where tag_id=(select id from tags where name='nodejs')")
+	assert.NoError(t, err)
+
+	added, err := s.MergeTags([]string{"nodejs"}, "node")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), added)
+
+	for word, score := range map[string]float64{"both": 15, "nodejs-only": 13} {
+		info, err := s.Word(word)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]float64{"node": score}, info.TagScores, word)
+	}
+}
+
+func TestMergeTagsUndoHits(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"both", "nodejs-only"}, []string{"nodejs"}))
+	assert.NoError(t, s.AddWords([]string{"both"}, []string{"node"}))
+	batch, err := s.NewBatch("feedback")
+	assert.NoError(t, err)
+	_, err = s.RecordHits(map[string]int64{"both": 2, "nodejs-only": 1}, []string{"nodejs", "node"}, batch)
+	assert.NoError(t, err)
+
+	_, err = s.MergeTags([]string{"nodejs"}, "node")
+	assert.NoError(t, err)
+	both, err := s.Word("both")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"node": 4}, both.TagScores)
+
+	// the hits moved to node along with the associations are taken back, the associations older than the batch are kept
+	undone, err := s.UndoBatch(batch)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), undone.Hits)
+	for _, word := range []string{"both", "nodejs-only"} {
+		info, err := s.Word(word)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), info.Hits, word)
+		assert.Equal(t, []string{"node"}, info.Tags, word)
+		assert.Empty(t, info.TagScores, word)
+	}
+	assert.Equal(t, int64(0), countRows(t, s, "batch_hits"))
+}
+
+func TestDeleteTag(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"one", "two"}, []string{"a", "b"}))
+
+	links, err := s.DeleteTag("a")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), links)
+	assert.Equal(t, int64(1), countRows(t, s, "tags"))
+	assert.Equal(t, int64(2), countRows(t, s, "wt"))
+	assert.Equal(t, int64(2), countRows(t, s, "words"))
+
+	_, err = s.DeleteTag("a")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
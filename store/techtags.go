@@ -0,0 +1,154 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// TechTagsInfo maps the technologies matching a pattern to tags
+type TechTagsInfo struct {
+	// Pattern is a lowercase glob pattern matched against the technology names, ie apache*tomcat
+	Pattern string   `json:"pattern"`
+	Tags    []string `json:"tags"`
+}
+
+// DefaultTechTags is the mapping of technologies to tags restored by ResetTechTags
+var DefaultTechTags = []TechTagsInfo{
+	{"nginx", []string{"nginx"}},
+	{"openresty", []string{"nginx"}},
+	{"apache", []string{"apache"}},
+	{"apache http server", []string{"apache"}},
+	{"apache tomcat", []string{"tomcat", "java", "jsp"}},
+	{"tomcat", []string{"tomcat", "java", "jsp"}},
+	{"microsoft iis", []string{"iis", "asp", "windows"}},
+	{"iis", []string{"iis", "asp", "windows"}},
+	{"microsoft asp.net", []string{"aspnet", "asp", "windows"}},
+	{"asp.net", []string{"aspnet", "asp", "windows"}},
+	{"*sharepoint*", []string{"sharepoint", "aspnet"}},
+	{"litespeed", []string{"litespeed"}},
+	{"caddy", []string{"caddy"}},
+	{"php", []string{"php"}},
+	{"drupal", []string{"drupal", "php"}},
+	{"wordpress", []string{"wordpress", "php"}},
+	{"joomla", []string{"joomla", "php"}},
+	{"magento", []string{"magento", "php"}},
+	{"laravel", []string{"laravel", "php"}},
+	{"symfony", []string{"symfony", "php"}},
+	{"codeigniter", []string{"codeigniter", "php"}},
+	{"python", []string{"python"}},
+	{"django", []string{"django", "python"}},
+	{"flask", []string{"flask", "python"}},
+	{"ruby", []string{"ruby"}},
+	{"ruby on rails", []string{"rails", "ruby"}},
+	{"node.js", []string{"nodejs"}},
+	{"express", []string{"express", "nodejs"}},
+	{"next.js", []string{"nextjs", "nodejs"}},
+	{"java", []string{"java"}},
+	{"jboss*", []string{"jboss", "java"}},
+	{"wildfly", []string{"jboss", "java"}},
+	{"spring*", []string{"spring", "java"}},
+	{"*coldfusion", []string{"coldfusion"}},
+	{"jenkins", []string{"jenkins", "java"}},
+	{"grafana", []string{"grafana"}},
+	{"kibana", []string{"kibana"}},
+	{"elasticsearch", []string{"elasticsearch"}},
+	{"graphql", []string{"graphql", "api"}},
+	{"swagger ui", []string{"swagger", "api"}},
+}
+
+// Insert mappings of technologies to tags, replacing the tags of their patterns
+func insertTechTags(tx *sql.Tx, mappings []TechTagsInfo) error {
+	for _, m := range mappings {
+		if _, err := tx.Exec("delete from tech_tags where pattern=?", m.Pattern); err != nil {
+			return err
+		}
+		for _, tag := range m.Tags {
+			if _, err := tx.Exec("insert or ignore into tech_tags(pattern,tag) values(?,?)", m.Pattern, tag); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Query the mappings of the patterns selected by where
+func techTags(db *sql.DB, where string, args ...interface{}) ([]TechTagsInfo, error) {
+	rows, err := db.Query("select pattern,group_concat(tag) from (select pattern,tag from tech_tags "+where+" order by pattern,rowid) group by pattern order by pattern", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mappings := []TechTagsInfo{}
+	for rows.Next() {
+		var m TechTagsInfo
+		var tags string
+		if err = rows.Scan(&m.Pattern, &tags); err != nil {
+			return nil, err
+		}
+		m.Tags = strings.Split(tags, ",")
+		mappings = append(mappings, m)
+	}
+	return mappings, rows.Err()
+}
+
+// TechTags lists the mappings of technologies to tags, ordered by pattern
+func (s *Store) TechTags() ([]TechTagsInfo, error) {
+	return techTags(s.db, "")
+}
+
+// MatchTechTags returns the mappings whose pattern matches a technology name, case insensitively
+func (s *Store) MatchTechTags(tech string) ([]TechTagsInfo, error) {
+	return techTags(s.db, "where lower(?) GLOB pattern", strings.TrimSpace(tech))
+}
+
+// SetTechTags maps the technologies matching a pattern to tags, replacing the tags of the pattern
+func (s *Store) SetTechTags(pattern string, tags []string) error {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return fmt.Errorf("the technology pattern can not be empty")
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("technology pattern %q needs at least one tag", pattern)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = insertTechTags(tx, []TechTagsInfo{{pattern, tags}}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteTechTags removes the mapping of a pattern
+func (s *Store) DeleteTechTags(pattern string) error {
+	result, err := s.db.Exec("delete from tech_tags where pattern=?", strings.ToLower(strings.TrimSpace(pattern)))
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("technology pattern %q: %w", pattern, ErrNotFound)
+	}
+	return nil
+}
+
+// ResetTechTags replaces the mappings of technologies to tags with DefaultTechTags
+func (s *Store) ResetTechTags() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("delete from tech_tags"); err != nil {
+		return err
+	}
+	if err = insertTechTags(tx, DefaultTechTags); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTechTags(t *testing.T) {
+	s := newTestStore(t)
+	mappings, err := s.TechTags()
+	assert.NoError(t, err)
+	// new databases start with the mapping of their migration, the same as the defaults for now
+	assert.ElementsMatch(t, DefaultTechTags, mappings)
+
+	matches, err := s.MatchTechTags("Apache Tomcat")
+	assert.NoError(t, err)
+	assert.Equal(t, []TechTagsInfo{{Pattern: "apache tomcat", Tags: []string{"tomcat", "java", "jsp"}}}, matches)
+	matches, err = s.MatchTechTags("Microsoft SharePoint Server")
+	assert.NoError(t, err)
+	assert.Equal(t, []TechTagsInfo{{Pattern: "*sharepoint*", Tags: []string{"sharepoint", "aspnet"}}}, matches)
+	matches, err = s.MatchTechTags("Unknown")
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+
+	assert.NoError(t, s.SetTechTags(" Apache* ", []string{"apache", "httpd"}))
+	matches, err = s.MatchTechTags("Apache Tomcat")
+	assert.NoError(t, err)
+	assert.Equal(t, []TechTagsInfo{
+		{Pattern: "apache tomcat", Tags: []string{"tomcat", "java", "jsp"}},
+		{Pattern: "apache*", Tags: []string{"apache", "httpd"}},
+	}, matches)
+	assert.NoError(t, s.SetTechTags("apache*", []string{"httpd"}))
+	assert.Error(t, s.SetTechTags("apache*", nil))
+	assert.Error(t, s.SetTechTags(" ", []string{"x"}))
+
+	assert.NoError(t, s.DeleteTechTags("apache*"))
+	assert.ErrorIs(t, s.DeleteTechTags("apache*"), ErrNotFound)
+	assert.NoError(t, s.DeleteTechTags("php"))
+
+	assert.NoError(t, s.SetTechTags("custom", []string{"x"}))
+	assert.NoError(t, s.ResetTechTags())
+	mappings, err = s.TechTags()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, DefaultTechTags, mappings)
+}
@@ -0,0 +1,7 @@
+-- layout of the early prototypes, without a sysconfig table
+create table words (id integer not null primary key AUTOINCREMENT, name text NOT NULL UNIQUE);
+create table tags (id integer not null primary key AUTOINCREMENT, name text NOT NULL UNIQUE);
+create table wt (word_id integer not null , tag_id integer not null, FOREIGN KEY(word_id) REFERENCES words(id),FOREIGN KEY(tag_id) REFERENCES tags(id),PRIMARY KEY(word_id,tag_id));
+insert into words(id,name) values (1,'admin'),(2,'login'),(3,'untagged');
+insert into tags(id,name) values (1,'drupal'),(2,'php');
+insert into wt(word_id,tag_id) values (1,1),(1,2),(2,2);
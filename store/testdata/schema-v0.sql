@@ -0,0 +1,10 @@
+-- layout created by createDB before schema versioning was introduced
+create table IF NOT EXISTS words (id integer not null primary key AUTOINCREMENT, name text NOT NULL UNIQUE);
+create table IF NOT EXISTS tags (id integer not null primary key AUTOINCREMENT, name text NOT NULL UNIQUE);
+create table IF NOT EXISTS wt (word_id integer not null , tag_id integer not null, FOREIGN KEY(word_id) REFERENCES words(id),FOREIGN KEY(tag_id) REFERENCES tags(id),PRIMARY KEY(word_id,tag_id));
+create table IF NOT EXISTS sysconfig(name text not null primary key, val text);
+insert or ignore into sysconfig(name,val) values ("version","0.0.0"),("dbname","default");
+insert into sysconfig(name,val) values ("description","fixture v0");
+insert into words(id,name) values (1,'admin'),(2,'login'),(3,'untagged');
+insert into tags(id,name) values (1,'drupal'),(2,'php');
+insert into wt(word_id,tag_id) values (1,1),(1,2),(2,2);
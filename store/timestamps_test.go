@@ -0,0 +1,70 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchSinceUntil(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"old", "retagged"}, []string{"php"}))
+	assert.NoError(t, s.AddWords([]string{"new"}, []string{"php"}))
+	assert.NoError(t, s.AddWords([]string{"retagged"}, []string{"api"}))
+
+	// age everything but the last two imports by 30 days
+	month := int64(30 * 24 * 60 * 60)
+	_, err := s.DB().Exec("update words set created_at=created_at-? where name in ('old','retagged')", month)
+	assert.NoError(t, err)
+	_, err = s.DB().Exec("update wt set created_at=created_at-? where batch_id=1", month)
+	assert.NoError(t, err)
+
+	words := func(query string, since, until time.Time) []string {
+		opts := SearchOptions{Since: since, Until: until}
+		if query != "" {
+			opts.Query, _ = ParseQuery(query)
+		}
+		var names []string
+		for _, rec := range search(t, s, opts) {
+			names = append(names, rec.Word)
+		}
+		return names
+	}
+	week := time.Now().Add(-7 * 24 * time.Hour)
+	assert.Equal(t, []string{"new"}, words("", week, time.Time{}))
+	assert.Equal(t, []string{"old", "retagged"}, words("", time.Time{}, week))
+	assert.Equal(t, []string{"retagged"}, words("api", week, time.Time{}))
+	assert.Equal(t, []string{"new"}, words("php", week, time.Time{}))
+	assert.Equal(t, []string{"retagged", "new"}, words("php or api", week, time.Time{}))
+	assert.Equal(t, []string{"old", "retagged"}, words("php", time.Time{}, week))
+	assert.Empty(t, words("api", time.Time{}, week))
+
+	// the negated tags do not take part in the range, without included tags it applies to the words
+	assert.Equal(t, []string{"new"}, words("not api", week, time.Time{}))
+	assert.Equal(t, []string{"old"}, words("not api", time.Time{}, week))
+	assert.Equal(t, []string{"new"}, words("php and not api", week, time.Time{}))
+}
+
+func TestWordTimestamps(t *testing.T) {
+	s := newTestStore(t)
+	before := time.Now().Add(-time.Second)
+	_, err := s.ImportReader(strings.NewReader("admin\n"), ImportOptions{Tags: []string{"a"}})
+	assert.NoError(t, err)
+	_, err = s.DB().Exec("update words set created_at=created_at-100, updated_at=updated_at-100")
+	assert.NoError(t, err)
+
+	info, err := s.Word("admin")
+	assert.NoError(t, err)
+	assert.True(t, info.CreatedAt.Before(before))
+	assert.Equal(t, info.CreatedAt, info.UpdatedAt)
+
+	// a new association updates the word but keeps its creation time
+	_, err = s.ImportReader(strings.NewReader("admin\n"), ImportOptions{Tags: []string{"b"}})
+	assert.NoError(t, err)
+	updated, err := s.Word("admin")
+	assert.NoError(t, err)
+	assert.Equal(t, info.CreatedAt, updated.CreatedAt)
+	assert.False(t, updated.UpdatedAt.Before(before))
+}
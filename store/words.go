@@ -0,0 +1,302 @@
+package store
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The number of lines removed per transaction by RemoveReader
+const removeBatchSize = 4000
+
+// Gets the ID of a given word, or -1 when the word does not exist
+func wordID(q queryer, word string) (int64, error) {
+	var id int64
+	err := q.QueryRow("select id from words where name = ?", word).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return -1, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+// WordID returns the ID of a given word, or -1 when the word does not exist
+func (s *Store) WordID(word string) (int64, error) {
+	return wordID(s.db, word)
+}
+
+// AddWords adds the given words to the database, associated with the given tags
+func (s *Store) AddWords(words []string, tags []string) error {
+	batchID, err := s.NewBatch("add")
+	if err != nil {
+		return err
+	}
+	_, err = s.bulkImport(ImportOptions{Tags: tags, Source: AddSource, BatchID: batchID}, func(b *bulkImporter) error {
+		for _, word := range words {
+			log.Debugln("[AddWords] adding word:", word)
+			if err := b.add(word); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// lineProcessor handles the lines read from a file within a transaction
+type lineProcessor interface {
+	process(line string) error
+	close()
+}
+
+// Stream the non blank lines of r through the processors created by newProcessor,
+// committing every removeBatchSize lines. Compressed content is decompressed on the fly.
+// It returns the number of words processed.
+func (s *Store) processLines(r io.Reader, newProcessor func(tx *sql.Tx) (lineProcessor, error)) (int64, error) {
+	dr, err := Decompress(r)
+	if err != nil {
+		return 0, err
+	}
+	defer dr.Close()
+
+	var count int64
+	var tx *sql.Tx
+	var processor lineProcessor
+	// commit the current transaction, if any, and optionally start a new one
+	flush := func(next bool) error {
+		if tx != nil {
+			processor.close()
+			err := tx.Commit()
+			tx, processor = nil, nil
+			if err != nil {
+				return err
+			}
+		}
+		if !next {
+			return nil
+		}
+		var err error
+		if tx, err = s.db.Begin(); err != nil {
+			return err
+		}
+		if processor, err = newProcessor(tx); err != nil {
+			tx.Rollback()
+			tx = nil
+			return err
+		}
+		return nil
+	}
+	defer func() {
+		if tx != nil {
+			processor.close()
+			tx.Rollback()
+		}
+	}()
+
+	if err := flush(true); err != nil {
+		return 0, err
+	}
+	scanner := bufio.NewScanner(dr)
+	for lines := 1; scanner.Scan(); lines++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) != "" {
+			if err := processor.process(line); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if lines%removeBatchSize == 0 {
+			log.Infoln("Lines:", lines)
+			if err := flush(true); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+	return count, flush(false)
+}
+
+// WordInfo describes a word along with its tags and the sources it was imported from
+type WordInfo struct {
+	ID      int64        `json:"id"`
+	Name    string       `json:"word"`
+	Tags    []string     `json:"tags"`
+	Sources []SourceInfo `json:"sources"`
+	// CreatedAt is when the word was first imported, UpdatedAt when its tags last changed
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Score is the score of the word along with its hits and the number of its sources,
+	// TagScores the scores of its associations along with their hits
+	Score     float64            `json:"score"`
+	TagScores map[string]float64 `json:"tag_scores"`
+	// Hits counts the times the word was found by a fuzzer, see RecordHits
+	Hits int64 `json:"hits"`
+}
+
+// Word returns the details of a single word
+func (s *Store) Word(name string) (WordInfo, error) {
+	info := WordInfo{Name: name, Tags: []string{}, TagScores: map[string]float64{}}
+	id, err := wordID(s.db, name)
+	if err != nil {
+		return info, err
+	}
+	if id <= 0 {
+		return info, fmt.Errorf("word %q: %w", name, ErrNotFound)
+	}
+	info.ID = id
+
+	var createdAt, updatedAt int64
+	if err = s.db.QueryRow("select created_at,updated_at,score+hits+"+sourceCountSQL("words.id")+",hits from words where id=?", id).Scan(&createdAt, &updatedAt, &info.Score, &info.Hits); err != nil {
+		return info, err
+	}
+	info.CreatedAt, info.UpdatedAt = time.Unix(createdAt, 0), time.Unix(updatedAt, 0)
+
+	rows, err := s.db.Query("select t.name,wt.score+wt.hits from tags as t join wt on wt.tag_id=t.id where wt.word_id=? order by t.name", id)
+	if err != nil {
+		return info, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tag string
+		var score float64
+		if err = rows.Scan(&tag, &score); err != nil {
+			return info, err
+		}
+		info.Tags = append(info.Tags, tag)
+		if score != 0 {
+			info.TagScores[tag] = score
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return info, err
+	}
+	info.Sources, err = wordSources(s.db, id)
+	return info, err
+}
+
+// UntagWords removes the associations between the given words and tags, the words are kept.
+// It returns the number of associations removed.
+func (s *Store) UntagWords(words []string, tags []string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("delete from wt where word_id=(select id from words where name=?) and tag_id=(select id from tags where name=?)")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var removed int64
+	for _, word := range words {
+		for _, tag := range tags {
+			result, err := stmt.Exec(strings.TrimSpace(word), tag)
+			if err != nil {
+				return 0, err
+			}
+			n, _ := result.RowsAffected()
+			removed += n
+		}
+	}
+	return removed, tx.Commit()
+}
+
+// wordRemover deletes words along with their associations within a transaction
+type wordRemover struct {
+	wtStmt      *sql.Stmt
+	sourcesStmt *sql.Stmt
+	wordsStmt   *sql.Stmt
+	removed     *int64
+}
+
+func newWordRemover(tx *sql.Tx, removed *int64) (*wordRemover, error) {
+	wtStmt, err := tx.Prepare("delete from wt where word_id=(select id from words where name=?)")
+	if err != nil {
+		return nil, err
+	}
+	sourcesStmt, err := tx.Prepare("delete from word_sources where word_id=(select id from words where name=?)")
+	if err != nil {
+		wtStmt.Close()
+		return nil, err
+	}
+	wordsStmt, err := tx.Prepare("delete from words where name=?")
+	if err != nil {
+		wtStmt.Close()
+		sourcesStmt.Close()
+		return nil, err
+	}
+	return &wordRemover{wtStmt: wtStmt, sourcesStmt: sourcesStmt, wordsStmt: wordsStmt, removed: removed}, nil
+}
+
+func (w *wordRemover) process(word string) error {
+	word = strings.TrimSpace(word)
+	log.Debugln("[wordRemover] removing word:", word)
+	if _, err := w.wtStmt.Exec(word); err != nil {
+		return err
+	}
+	if _, err := w.sourcesStmt.Exec(word); err != nil {
+		return err
+	}
+	result, err := w.wordsStmt.Exec(word)
+	if err != nil {
+		return err
+	}
+	n, _ := result.RowsAffected()
+	*w.removed += n
+	return nil
+}
+
+func (w *wordRemover) close() {
+	w.wtStmt.Close()
+	w.sourcesStmt.Close()
+	w.wordsStmt.Close()
+}
+
+// RemoveWords deletes the given words along with their associations.
+// It returns the number of words removed.
+func (s *Store) RemoveWords(words []string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var removed int64
+	remover, err := newWordRemover(tx, &removed)
+	if err != nil {
+		return 0, err
+	}
+	defer remover.close()
+
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word == "" {
+			continue
+		}
+		if err = remover.process(word); err != nil {
+			return 0, err
+		}
+	}
+	return removed, tx.Commit()
+}
+
+// RemoveReader deletes the words read one per line from r along with their associations.
+// Compressed content is detected as with ImportReader. It returns the number of words removed.
+func (s *Store) RemoveReader(r io.Reader) (int64, error) {
+	var removed int64
+	_, err := s.processLines(r, func(tx *sql.Tx) (lineProcessor, error) {
+		return newWordRemover(tx, &removed)
+	})
+	return removed, err
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// count the rows of a table
+func countRows(t *testing.T, s *Store, table string) int64 {
+	t.Helper()
+	var n int64
+	if err := s.DB().QueryRow("select count(*) from " + table).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestWordID(t *testing.T) {
+	s := newTestStore(t)
+
+	id, err := s.WordID("word")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), id)
+
+	assert.NoError(t, s.AddWords([]string{"word"}, nil))
+	id, err = s.WordID("word")
+	assert.NoError(t, err)
+	assert.Greater(t, id, int64(0))
+}
+
+func TestAddWords(t *testing.T) {
+	s := newTestStore(t)
+
+	assert.NoError(t, s.AddWords([]string{"word1", " word2 ", ""}, []string{"a", "b", "c"}))
+	assert.Equal(t, int64(3), countRows(t, s, "tags"))
+	assert.Equal(t, int64(2), countRows(t, s, "words"))
+	assert.Equal(t, int64(2*3), countRows(t, s, "wt"))
+
+	// existing words get their tags updated to include old and new ones
+	assert.NoError(t, s.AddWords([]string{"word1", "word3"}, []string{"c", "d"}))
+	assert.Equal(t, int64(4), countRows(t, s, "tags"))
+	assert.Equal(t, int64(3), countRows(t, s, "words"))
+	assert.Equal(t, int64(2*3+3), countRows(t, s, "wt"))
+}
+
+func TestWord(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"admin"}, []string{"php", "drupal"}))
+	assert.NoError(t, s.AddWords([]string{"bare"}, nil))
+
+	info, err := s.Word("admin")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), info.ID)
+	assert.Equal(t, "admin", info.Name)
+	assert.Equal(t, []string{"drupal", "php"}, info.Tags)
+	if assert.Len(t, info.Sources, 1) {
+		assert.Equal(t, AddSource, info.Sources[0].Path)
+		assert.Equal(t, []string{"php", "drupal"}, info.Sources[0].Tags)
+	}
+
+	info, err = s.Word("bare")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, info.Tags)
+
+	_, err = s.Word("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestUntagWords(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"one", "two"}, []string{"a", "b"}))
+
+	removed, err := s.UntagWords([]string{"one", "two", "missing"}, []string{"a", "missing"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), removed)
+	assert.Equal(t, int64(2), countRows(t, s, "words"))
+	assert.Equal(t, int64(2), countRows(t, s, "wt"))
+}
+
+func TestRemoveWords(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.AddWords([]string{"one", "two", "three"}, []string{"a", "b"}))
+
+	removed, err := s.RemoveWords([]string{"one", " ", "missing"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+	assert.Equal(t, int64(2), countRows(t, s, "words"))
+	assert.Equal(t, int64(4), countRows(t, s, "wt"))
+}
+
+func TestRemoveReader(t *testing.T) {
+	s := newTestStore(t)
+
+	var lines []string
+	for i := 0; i < removeBatchSize+10; i++ {
+		lines = append(lines, fmt.Sprint("word", i))
+	}
+	_, err := s.ImportReader(strings.NewReader(strings.Join(lines, "\n")), ImportOptions{Tags: []string{"a"}})
+	assert.NoError(t, err)
+
+	removed, err := s.RemoveReader(strings.NewReader(strings.Join(lines[5:], "\n") + "\nmissing\n\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(removeBatchSize+5), removed)
+	assert.Equal(t, int64(5), countRows(t, s, "words"))
+	assert.Equal(t, int64(5), countRows(t, s, "wt"))
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// parse args of the tag subcommand and exec the requested action
+func tagSubcmd(args []string) error {
+	tagCmd := flag.NewFlagSet("tag", flag.ContinueOnError)
+
+	tagCmd.SetOutput(flag.CommandLine.Output())
+
+	tagCmd.Usage = func() {
+		fmt.Fprint(tagCmd.Output(), "Manage the tags of the database\n\n")
+		fmt.Fprintln(tagCmd.Output(), "Usage of orunmila tag:")
+		fmt.Fprintln(tagCmd.Output(), "orunmila [-db <db_path>] [-debug] tag <action> [arguments]")
+		fmt.Fprintln(tagCmd.Output(), "\nActions")
+		fmt.Fprintln(tagCmd.Output(), "  ls                              List the tags along with their word counts")
+		fmt.Fprintln(tagCmd.Output(), "  rename <old> <new>              Rename a tag")
+		fmt.Fprintln(tagCmd.Output(), "  merge -into <tag> <tags>...     Move the words of the given tags over to another tag and delete them")
+		fmt.Fprintln(tagCmd.Output(), "  rm <tags>...                    Delete tags along with their word associations")
+		fmt.Fprintln(tagCmd.Output(), "  describe <tag> [description]    Display or set the description of a tag")
+	}
+
+	return runAction(tagCmd, args, []subcmdAction{
+		{names: []string{"ls", "list"}, run: tagLs},
+		{names: []string{"rename", "mv"}, run: tagRename},
+		{names: []string{"merge"}, run: tagMerge},
+		{names: []string{"rm", "remove", "delete"}, run: tagRm},
+		{names: []string{"describe", "des"}, run: tagDescribe},
+	})
+}
+
+// list the tags along with their word counts
+func tagLs(args []string) error {
+	cmd := newActionFlagSet("tag", "ls", "ls [-n]", "List the tags along with their word counts")
+	namesOnlyPtr := cmd.Bool("n", false, "print only the tag names")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+
+	db := openStoreReadOnly()
+	defer db.Close()
+
+	tags, err := db.Tags()
+	if err != nil {
+		return err
+	}
+	if *namesOnlyPtr {
+		for _, tag := range tags {
+			fmt.Println(tag.Name)
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TAG\tWORDS\tDESCRIPTION")
+	for _, tag := range tags {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", tag.Name, tag.Words, tag.Description)
+	}
+	return w.Flush()
+}
+
+// rename a tag
+func tagRename(args []string) error {
+	cmd := newActionFlagSet("tag", "rename", "rename <old> <new>", "Rename a tag, use merge when the new name already exists")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	if cmd.NArg() != 2 {
+		cmd.Usage()
+		return fmt.Errorf("rename needs the old and the new tag name")
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	if err := db.RenameTag(cmd.Arg(0), cmd.Arg(1)); err != nil {
+		return err
+	}
+	log.Infof("[tagRename] renamed tag %q to %q", cmd.Arg(0), cmd.Arg(1))
+	return nil
+}
+
+// merge tags into another one
+func tagMerge(args []string) error {
+	cmd := newActionFlagSet("tag", "merge", "merge -into <tag> <tags>...", "Move the words of the given tags over to another tag and delete them")
+	intoPtr := cmd.String("into", "", "the tag to merge into, created if it does not exist")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	into := strings.TrimSpace(*intoPtr)
+	if into == "" || cmd.NArg() == 0 {
+		cmd.Usage()
+		return fmt.Errorf("merge needs a -into tag and at least one tag to merge")
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	added, err := db.MergeTags(cmd.Args(), into)
+	if err != nil {
+		return err
+	}
+	log.Infof("[tagMerge] merged %v into %q, %d new associations", cmd.Args(), into, added)
+	return nil
+}
+
+// delete tags along with their associations
+func tagRm(args []string) error {
+	cmd := newActionFlagSet("tag", "rm", "rm <tags>...", "Delete tags along with their word associations, the words are kept")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	if cmd.NArg() == 0 {
+		cmd.Usage()
+		return fmt.Errorf("rm needs at least one tag")
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	for _, tag := range cmd.Args() {
+		links, err := db.DeleteTag(tag)
+		if err != nil {
+			return err
+		}
+		log.Infof("[tagRm] deleted tag %q and %d associations", tag, links)
+	}
+	return nil
+}
+
+// display or set the description of a tag
+func tagDescribe(args []string) error {
+	cmd := newActionFlagSet("tag", "describe", "describe <tag> [description]", "Display or set the description of a tag")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	if cmd.NArg() == 0 {
+		cmd.Usage()
+		return fmt.Errorf("describe needs a tag")
+	}
+
+	if cmd.NArg() == 1 {
+		db := openStoreReadOnly()
+		defer db.Close()
+
+		tag, err := db.Tag(cmd.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("[name]: %s\n[words]: %d\n[description]: %s\n", tag.Name, tag.Words, tag.Description)
+		return nil
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	return db.DescribeTag(cmd.Arg(0), strings.Join(cmd.Args()[1:], " "))
+}
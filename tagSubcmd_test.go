@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagSubcmdActions(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+
+	addSubcmd([]string{"-tags", "nodejs,wrong", "word1", "word2"})
+	addSubcmd([]string{"-tags", "node", "word2", "word3"})
+
+	assert.NoError(t, tagSubcmd([]string{"ls"}))
+	assert.NoError(t, tagSubcmd([]string{"merge", "-into", "node", "nodejs"}))
+	assert.NoError(t, tagSubcmd([]string{"rename", "wrong", "right"}))
+	assert.NoError(t, tagSubcmd([]string{"describe", "node", "Node.js", "applications"}))
+	assert.NoError(t, tagSubcmd([]string{"describe", "node"}))
+
+	db := openStore()
+	defer db.Close()
+	tags, err := db.Tags()
+	assert.NoError(t, err)
+	if assert.Len(t, tags, 2) {
+		assert.Equal(t, "node", tags[0].Name)
+		assert.Equal(t, int64(3), tags[0].Words)
+		assert.Equal(t, "Node.js applications", tags[0].Description)
+		assert.Equal(t, "right", tags[1].Name)
+	}
+
+	assert.NoError(t, tagSubcmd([]string{"rm", "right"}))
+	assert.Error(t, tagSubcmd([]string{"rm", "right"}))
+	assert.Error(t, tagSubcmd([]string{"rename", "node"}))
+	assert.Error(t, tagSubcmd([]string{"merge", "node"}))
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// parse args of the techmap subcommand and exec the requested action
+func techmapSubcmd(args []string) error {
+	techmapCmd := flag.NewFlagSet("techmap", flag.ContinueOnError)
+
+	techmapCmd.SetOutput(flag.CommandLine.Output())
+
+	techmapCmd.Usage = func() {
+		fmt.Fprint(techmapCmd.Output(), "Manage the mapping of detected technologies to tags used by orunmila recommend\n\n")
+		fmt.Fprintln(techmapCmd.Output(), "Usage of orunmila techmap:")
+		fmt.Fprintln(techmapCmd.Output(), "orunmila [-db <db_path>] [-debug] techmap <action> [arguments]")
+		fmt.Fprintln(techmapCmd.Output(), "\nActions")
+		fmt.Fprintln(techmapCmd.Output(), "  ls                              List the technology patterns along with their tags")
+		fmt.Fprintln(techmapCmd.Output(), "  set <pattern> <tags>            Map the technologies matching a glob pattern to tags")
+		fmt.Fprintln(techmapCmd.Output(), "  rm <patterns>...                Delete technology patterns")
+		fmt.Fprintln(techmapCmd.Output(), "  test <technologies>...          Display the tags of technologies")
+		fmt.Fprintln(techmapCmd.Output(), "  reset                           Restore the default mapping")
+	}
+
+	return runAction(techmapCmd, args, []subcmdAction{
+		{names: []string{"ls", "list"}, run: techmapLs},
+		{names: []string{"set"}, run: techmapSet},
+		{names: []string{"rm", "delete"}, run: techmapRm},
+		{names: []string{"test"}, run: techmapTest},
+		{names: []string{"reset"}, run: techmapReset},
+	})
+}
+
+// list the technology patterns along with their tags
+func techmapLs(args []string) error {
+	cmd := newActionFlagSet("techmap", "ls", "ls", "List the technology patterns along with their tags")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+
+	db := openStoreReadOnly()
+	defer db.Close()
+
+	mappings, err := db.TechTags()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PATTERN\tTAGS")
+	for _, m := range mappings {
+		fmt.Fprintf(w, "%s\t%s\n", m.Pattern, strings.Join(m.Tags, ","))
+	}
+	return w.Flush()
+}
+
+// map the technologies matching a pattern to tags
+func techmapSet(args []string) error {
+	cmd := newActionFlagSet("techmap", "set", "set <pattern> <tags>", "Map the technologies matching a glob pattern, ie 'apache*tomcat', to a comma separated list of tags, replacing the tags of the pattern")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	if cmd.NArg() != 2 {
+		cmd.Usage()
+		return fmt.Errorf("set needs a pattern and a list of tags")
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	pattern, tags := cmd.Arg(0), splitList(cmd.Arg(1))
+	if err := db.SetTechTags(pattern, tags); err != nil {
+		return err
+	}
+	log.Infof("[techmapSet] technology pattern %q: %s", pattern, strings.Join(tags, ","))
+	return nil
+}
+
+// delete technology patterns
+func techmapRm(args []string) error {
+	cmd := newActionFlagSet("techmap", "rm", "rm <patterns>...", "Delete technology patterns")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	if cmd.NArg() == 0 {
+		cmd.Usage()
+		return fmt.Errorf("rm needs at least one pattern")
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	for _, pattern := range cmd.Args() {
+		if err := db.DeleteTechTags(pattern); err != nil {
+			return err
+		}
+		log.Infof("[techmapRm] deleted technology pattern %q", pattern)
+	}
+	return nil
+}
+
+// display the tags of technologies
+func techmapTest(args []string) error {
+	cmd := newActionFlagSet("techmap", "test", "test <technologies>...", "Display the patterns matching technologies along with their tags")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	if cmd.NArg() == 0 {
+		cmd.Usage()
+		return fmt.Errorf("test needs at least one technology")
+	}
+
+	db := openStoreReadOnly()
+	defer db.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TECHNOLOGY\tPATTERN\tTAGS")
+	for _, tech := range cmd.Args() {
+		mappings, err := db.MatchTechTags(tech)
+		if err != nil {
+			return err
+		}
+		for _, m := range mappings {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", tech, m.Pattern, strings.Join(m.Tags, ","))
+		}
+		if len(mappings) == 0 {
+			fmt.Fprintf(w, "%s\t-\t\n", tech)
+		}
+	}
+	return w.Flush()
+}
+
+// restore the default mapping
+func techmapReset(args []string) error {
+	cmd := newActionFlagSet("techmap", "reset", "reset", "Replace the mapping of technologies to tags with the default one")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	if err := db.ResetTechTags(); err != nil {
+		return err
+	}
+	log.Infoln("[techmapReset] restored the default technology patterns")
+	return nil
+}
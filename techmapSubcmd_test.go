@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/proditis/orunmila/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTechmapSubcmdActions(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+
+	assert.NoError(t, techmapSubcmd([]string{"set", "gitlab*", "gitlab,ruby"}))
+	assert.Error(t, techmapSubcmd([]string{"set", "gitlab*"}))
+	assert.NoError(t, techmapSubcmd([]string{"ls"}))
+	assert.NoError(t, techmapSubcmd([]string{"test", "GitLab CE", "Unknown"}))
+	assert.NoError(t, techmapSubcmd([]string{"rm", "gitlab*", "php"}))
+	assert.Error(t, techmapSubcmd([]string{"rm", "gitlab*"}))
+
+	db := openStore()
+	mappings, err := db.TechTags()
+	db.Close()
+	assert.NoError(t, err)
+	assert.Len(t, mappings, len(store.DefaultTechTags)-1)
+
+	assert.NoError(t, techmapSubcmd([]string{"reset"}))
+}
@@ -0,0 +1,25 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// terminal is not implemented on this platform
+type terminal struct{}
+
+func openTerminal() (*terminal, error) {
+	return nil, errors.New("the terminal UI is not supported on this platform")
+}
+
+func (t *terminal) size() (int, int) { return 80, 24 }
+
+func (t *terminal) Read(p []byte) (int, error) { return 0, errors.New("no terminal") }
+
+func (t *terminal) Write(p []byte) (int, error) { return 0, errors.New("no terminal") }
+
+func (t *terminal) notifyResize(c chan<- os.Signal) {}
+
+func (t *terminal) Close() error { return nil }
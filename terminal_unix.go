@@ -0,0 +1,76 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// terminal is the controlling terminal of the process, in raw mode, so that the
+// standard input and output stay free for the words
+type terminal struct {
+	tty   *os.File
+	saved unix.Termios
+}
+
+// Open the controlling terminal and switch it to raw mode, keys are read one at a time without echo
+func openTerminal() (*terminal, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	termios, err := unix.IoctlGetTermios(int(tty.Fd()), ioctlReadTermios)
+	if err != nil {
+		tty.Close()
+		return nil, err
+	}
+	t := &terminal{tty: tty, saved: *termios}
+
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB
+	termios.Cflag |= unix.CS8
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+	if err = unix.IoctlSetTermios(int(tty.Fd()), ioctlWriteTermios, termios); err != nil {
+		tty.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// The number of columns and rows of the terminal, 80x24 when unknown
+func (t *terminal) size() (int, int) {
+	ws, err := unix.IoctlGetWinsize(int(t.tty.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 || ws.Row == 0 {
+		return 80, 24
+	}
+	return int(ws.Col), int(ws.Row)
+}
+
+func (t *terminal) Read(p []byte) (int, error) {
+	return t.tty.Read(p)
+}
+
+func (t *terminal) Write(p []byte) (int, error) {
+	return t.tty.Write(p)
+}
+
+// Relay the resizes of the terminal to c
+func (t *terminal) notifyResize(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGWINCH)
+}
+
+// Restore the terminal mode and close it
+func (t *terminal) Close() error {
+	err := unix.IoctlSetTermios(int(t.tty.Fd()), ioctlWriteTermios, &t.saved)
+	if closeErr := t.tty.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/proditis/orunmila/store"
+)
+
+// keyCode identifies the keys the terminal UI reacts to, keyRune being any printable character
+type keyCode int
+
+const (
+	keyUnknown keyCode = iota
+	keyRune
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyEnter
+	keyEscape
+	keyBackspace
+	keyCtrlC
+)
+
+// key is a key press
+type key struct {
+	code keyCode
+	r    rune
+}
+
+// The escape sequences of the special keys, after ESC
+var keySequences = map[string]keyCode{
+	"[A": keyUp, "OA": keyUp,
+	"[B": keyDown, "OB": keyDown,
+	"[5~": keyPageUp, "[6~": keyPageDown,
+	"[H": keyHome, "OH": keyHome, "[1~": keyHome, "[7~": keyHome,
+	"[F": keyEnd, "OF": keyEnd, "[4~": keyEnd, "[8~": keyEnd,
+}
+
+// Read a key press. A lone ESC is the escape key, while the bytes sent along with it are an escape sequence.
+func readKey(r *bufio.Reader) (key, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+	switch c {
+	case '\r', '\n':
+		return key{code: keyEnter}, nil
+	case 0x7f, 0x08:
+		return key{code: keyBackspace}, nil
+	case 0x03:
+		return key{code: keyCtrlC}, nil
+	case 0x1b:
+	default:
+		if c < ' ' {
+			return key{code: keyUnknown}, nil
+		}
+		return key{code: keyRune, r: c}, nil
+	}
+
+	if r.Buffered() == 0 {
+		return key{code: keyEscape}, nil
+	}
+	// CSI sequences end with a letter or ~, the SS3 ones are a single letter
+	var seq strings.Builder
+	for r.Buffered() > 0 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return key{}, err
+		}
+		seq.WriteByte(b)
+		if seq.Len() > 1 && (b == '~' || b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z') {
+			break
+		}
+	}
+	return key{code: keySequences[seq.String()]}, nil
+}
+
+// tagState is whether a tag is left out of the query, included or excluded
+type tagState int
+
+const (
+	tagNone tagState = iota
+	tagIncluded
+	tagExcluded
+)
+
+// The number of words fetched for the preview
+const tuiPreviewSize = 500
+
+// The action that ends the terminal UI
+type tuiAction int
+
+const (
+	tuiContinue tuiAction = iota
+	tuiWrite
+	tuiQuit
+)
+
+// tuiModel is the state of the terminal UI
+type tuiModel struct {
+	db *store.Store
+	// opts are the options of the search, the query being built from the tag states
+	opts   store.SearchOptions
+	tags   []store.TagInfo
+	states map[string]tagState
+	// all requires the words to have every included tag rather than any of them
+	all bool
+	// visible are the indexes of the tags matching the filter
+	visible []int
+	// cursor is the selected row of visible, offset the first displayed one
+	cursor, offset int
+	filter         string
+	filtering      bool
+	count          int64
+	preview        []string
+	err            error
+	action         tuiAction
+}
+
+// Create the model of the terminal UI, loading the tags of the database
+func newTUIModel(db *store.Store, opts store.SearchOptions) (*tuiModel, error) {
+	tags, err := db.Tags()
+	if err != nil {
+		return nil, err
+	}
+	m := &tuiModel{db: db, opts: opts, tags: tags, states: make(map[string]tagState)}
+	m.applyFilter()
+	return m, nil
+}
+
+// The query of the tag states, nil when no tag is selected
+func (m *tuiModel) query() store.Expr {
+	var included, excluded store.Expr
+	for _, tag := range m.tags {
+		switch m.states[tag.Name] {
+		case tagIncluded:
+			switch {
+			case included == nil:
+				included = store.Tag(tag.Name)
+			case m.all:
+				included = store.And(included, store.Tag(tag.Name))
+			default:
+				included = store.Or(included, store.Tag(tag.Name))
+			}
+		case tagExcluded:
+			if excluded == nil {
+				excluded = store.Not(store.Tag(tag.Name))
+			} else {
+				excluded = store.And(excluded, store.Not(store.Tag(tag.Name)))
+			}
+		}
+	}
+	switch {
+	case included == nil:
+		return excluded
+	case excluded == nil:
+		return included
+	}
+	return store.And(included, excluded)
+}
+
+// The search options of the current query
+func (m *tuiModel) searchOptions() store.SearchOptions {
+	opts := m.opts
+	opts.Query = m.query()
+	return opts
+}
+
+// Count the words of the current query and fetch the first ones
+func (m *tuiModel) refresh() {
+	opts := m.searchOptions()
+	m.count, m.preview = 0, nil
+	if m.count, m.err = m.db.Count(opts); m.err != nil {
+		return
+	}
+	if opts.Limit <= 0 || opts.Limit > tuiPreviewSize {
+		opts.Limit = tuiPreviewSize
+	}
+	m.err = m.db.Search(opts, func(rec store.Record) error {
+		m.preview = append(m.preview, rec.Word)
+		return nil
+	})
+}
+
+// Keep the tags whose name contains the filter
+func (m *tuiModel) applyFilter() {
+	m.visible = m.visible[:0]
+	filter := strings.ToLower(m.filter)
+	for i, tag := range m.tags {
+		if strings.Contains(strings.ToLower(tag.Name), filter) {
+			m.visible = append(m.visible, i)
+		}
+	}
+	m.cursor, m.offset = 0, 0
+}
+
+// Move the cursor by delta rows, within the visible tags
+func (m *tuiModel) move(delta int) {
+	m.cursor += delta
+	if m.cursor >= len(m.visible) {
+		m.cursor = len(m.visible) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// Set the state of the tag under the cursor, it returns false when there is none
+func (m *tuiModel) setState(next func(tagState) tagState) bool {
+	if len(m.visible) == 0 {
+		return false
+	}
+	name := m.tags[m.visible[m.cursor]].Name
+	if state := next(m.states[name]); state != tagNone {
+		m.states[name] = state
+	} else {
+		delete(m.states, name)
+	}
+	return true
+}
+
+// Handle a key press, rows being the number of tags displayed at once.
+// It returns whether the query changed.
+func (m *tuiModel) handleKey(k key, rows int) bool {
+	if k.code == keyCtrlC {
+		m.action = tuiQuit
+		return false
+	}
+	if m.filtering {
+		switch k.code {
+		case keyRune:
+			m.filter += string(k.r)
+			m.applyFilter()
+		case keyBackspace:
+			if m.filter != "" {
+				_, size := utf8.DecodeLastRuneInString(m.filter)
+				m.filter = m.filter[:len(m.filter)-size]
+				m.applyFilter()
+			}
+		case keyEnter:
+			m.filtering = false
+		case keyEscape:
+			m.filter, m.filtering = "", false
+			m.applyFilter()
+		case keyUp, keyDown, keyPageUp, keyPageDown, keyHome, keyEnd:
+			// leave the filter to move between the tags it kept
+			m.filtering = false
+		}
+		if m.filtering || k.code == keyEnter || k.code == keyEscape {
+			return false
+		}
+	}
+
+	switch k.code {
+	case keyUp:
+		m.move(-1)
+	case keyDown:
+		m.move(1)
+	case keyPageUp:
+		m.move(-rows)
+	case keyPageDown:
+		m.move(rows)
+	case keyHome:
+		m.move(-len(m.visible))
+	case keyEnd:
+		m.move(len(m.visible))
+	case keyEnter:
+		m.action = tuiWrite
+	case keyEscape:
+		if m.filter != "" {
+			m.filter = ""
+			m.applyFilter()
+		} else {
+			m.action = tuiQuit
+		}
+	case keyRune:
+		switch k.r {
+		case 'k':
+			m.move(-1)
+		case 'j':
+			m.move(1)
+		case 'g':
+			m.move(-len(m.visible))
+		case 'G':
+			m.move(len(m.visible))
+		case ' ':
+			return m.setState(func(s tagState) tagState { return (s + 1) % 3 })
+		case '+', 'i':
+			return m.setState(func(s tagState) tagState {
+				if s == tagIncluded {
+					return tagNone
+				}
+				return tagIncluded
+			})
+		case '-', 'x':
+			return m.setState(func(s tagState) tagState {
+				if s == tagExcluded {
+					return tagNone
+				}
+				return tagExcluded
+			})
+		case '/':
+			m.filtering = true
+		case 'a':
+			m.all = !m.all
+			return true
+		case 'c':
+			if len(m.states) == 0 {
+				return false
+			}
+			m.states = make(map[string]tagState)
+			return true
+		case 'q':
+			m.action = tuiQuit
+		}
+	}
+	return false
+}
+
+// ANSI escape sequences of the terminal UI
+const (
+	ansiReset   = "\x1b[0m"
+	ansiReverse = "\x1b[7m"
+	ansiBold    = "\x1b[1m"
+	ansiGreen   = "\x1b[32m"
+	ansiRed     = "\x1b[31m"
+	ansiDim     = "\x1b[2m"
+	// switch to the alternate screen and hide the cursor, and back
+	ansiEnter = "\x1b[?1049h\x1b[?25l"
+	ansiLeave = "\x1b[?25h\x1b[?1049l"
+)
+
+// Truncate or pad s to exactly width columns
+func fitWidth(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	n := utf8.RuneCountInString(s)
+	if n > width {
+		runes := []rune(s)
+		if width == 1 {
+			return "…"
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return s + strings.Repeat(" ", width-n)
+}
+
+// The number of header and footer lines around the tag list
+const tuiChromeLines = 5
+
+// Render the screen as width x height lines
+func (m *tuiModel) render(width, height int) []string {
+	rows := height - tuiChromeLines
+	if rows < 1 {
+		rows = 1
+	}
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+
+	match := "any"
+	if m.all {
+		match = "all"
+	}
+	title := fmt.Sprintf("orunmila tui  %d tags", len(m.tags))
+	if m.filter != "" {
+		title += fmt.Sprintf(", %d matching %q", len(m.visible), m.filter)
+	}
+	query := "(all words)"
+	if q := m.query(); q != nil {
+		query = q.String()
+	}
+	status := fmt.Sprintf("%d words", m.count)
+	if m.err != nil {
+		status = "error: " + m.err.Error()
+	}
+	lines := []string{
+		ansiBold + fitWidth(title, width) + ansiReset,
+		fitWidth(fmt.Sprintf("query: %s  (included tags match %s)", query, match), width),
+		ansiBold + fitWidth(status, width) + ansiReset,
+		ansiDim + strings.Repeat("─", width) + ansiReset,
+	}
+
+	left := width * 2 / 5
+	if left > 48 {
+		left = 48
+	}
+	right := width - left - 3
+	for row := 0; row < rows; row++ {
+		var tagCol string
+		if i := m.offset + row; i < len(m.visible) {
+			tag := m.tags[m.visible[i]]
+			marker, color := "   ", ""
+			switch m.states[tag.Name] {
+			case tagIncluded:
+				marker, color = "[+]", ansiGreen
+			case tagExcluded:
+				marker, color = "[-]", ansiRed
+			}
+			count := fmt.Sprint(tag.Words)
+			tagCol = marker + " " + fitWidth(tag.Name, left-len(count)-5) + " " + count
+			tagCol = fitWidth(tagCol, left)
+			if i == m.cursor {
+				color += ansiReverse
+			}
+			if color != "" {
+				tagCol = color + tagCol + ansiReset
+			}
+		} else {
+			tagCol = fitWidth("", left)
+		}
+		var word string
+		if row < len(m.preview) {
+			word = m.preview[row]
+		}
+		lines = append(lines, tagCol+" "+ansiDim+"│"+ansiReset+" "+fitWidth(word, right))
+	}
+
+	help := "↑↓ move  space cycle  + include  - exclude  / filter  a any/all  c clear  enter write  q quit"
+	if m.filtering {
+		help = "filter: " + m.filter + "▏  enter done, esc clear"
+	}
+	return append(lines, ansiDim+fitWidth(help, width)+ansiReset)
+}
+
+// Draw the screen on w
+func (m *tuiModel) draw(w io.Writer, width, height int) error {
+	_, err := io.WriteString(w, "\x1b[H"+strings.Join(m.render(width, height), "\x1b[K\r\n")+"\x1b[K\x1b[J")
+	return err
+}
+
+// errTUIQuit is returned when the terminal UI is left without writing the list
+var errTUIQuit = errors.New("quit without writing the list")
+
+// Run the terminal UI on a terminal until the list is to be written, or the UI is left
+func (m *tuiModel) run(term io.ReadWriter, size func() (int, int), resized <-chan os.Signal) error {
+	if _, err := io.WriteString(term, ansiEnter); err != nil {
+		return err
+	}
+	defer io.WriteString(term, ansiLeave)
+
+	type keyPress struct {
+		k   key
+		err error
+	}
+	keys, done := make(chan keyPress), make(chan struct{})
+	defer close(done)
+	go func() {
+		r := bufio.NewReader(term)
+		for {
+			k, err := readKey(r)
+			select {
+			case keys <- keyPress{k, err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	m.refresh()
+	for {
+		width, height := size()
+		if err := m.draw(term, width, height); err != nil {
+			return err
+		}
+		select {
+		case <-resized:
+		case press := <-keys:
+			if press.err != nil {
+				return press.err
+			}
+			if m.handleKey(press.k, height-tuiChromeLines) {
+				m.refresh()
+			}
+			switch m.action {
+			case tuiWrite:
+				return nil
+			case tuiQuit:
+				return errTUIQuit
+			}
+		}
+	}
+}
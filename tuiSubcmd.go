@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/proditis/orunmila/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// parse args of the tui subcommand and exec it
+func tuiSubcmd(args []string) error {
+	tuiCmd := flag.NewFlagSet("tui", flag.ContinueOnError)
+
+	tuiCmd.SetOutput(flag.CommandLine.Output())
+
+	tuiCmd.Usage = func() {
+		fmt.Fprint(tuiCmd.Output(), "Pick the tags of a wordlist interactively, previewing the matching words\n\n")
+		fmt.Fprintln(tuiCmd.Output(), "Usage of orunmila tui:")
+		fmt.Fprintf(tuiCmd.Output(), "orunmila [-db <db_path>] [-debug] tui [-out FILE] [-o FORMAT] [-sort ORDER] [-limit N]\n\n")
+		tuiCmd.PrintDefaults()
+		fmt.Fprintln(tuiCmd.Output(), "\nKeys")
+		fmt.Fprintln(tuiCmd.Output(), "  up, down, pgup, pgdn, home, end Move between the tags, j, k, g and G work too")
+		fmt.Fprintln(tuiCmd.Output(), "  space                           Cycle the tag through included, excluded and left out")
+		fmt.Fprintln(tuiCmd.Output(), "  + or i, - or x                  Include or exclude the tag")
+		fmt.Fprintln(tuiCmd.Output(), "  /                               Filter the tags by name, esc clears the filter")
+		fmt.Fprintln(tuiCmd.Output(), "  a                               Match all the included tags rather than any of them")
+		fmt.Fprintln(tuiCmd.Output(), "  c                               Clear the selection")
+		fmt.Fprintln(tuiCmd.Output(), "  enter                           Write the list and quit")
+		fmt.Fprintln(tuiCmd.Output(), "  q, esc, ctrl-c                  Quit without writing the list")
+		fmt.Fprintln(tuiCmd.Output(), "\nThe UI is drawn on the terminal, so the list can be piped into another tool")
+		fmt.Fprintln(tuiCmd.Output(), "\texample: orunmila tui -sort score | ffuf -w - -u https://target/FUZZ")
+	}
+
+	var (
+		outPtr    = tuiCmd.String("out", "", "write the list to this file (default: the standard output)")
+		outputPtr = tuiCmd.String("o", "plain", "the output format ("+strings.Join(outputFormats, "|")+")")
+		sortPtr   = tuiCmd.String("sort", "", "the order of the words ("+strings.Join(store.SortOrders, "|")+")")
+		limitPtr  = tuiCmd.Int("limit", 0, "the maximum number of words of the list (default: no limit)")
+	)
+
+	err := tuiCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	log.Debugln("[tuiSubcmd] using db:", *dbPtr)
+	log.Debugln("[tuiSubcmd] out:", *outPtr, "output format:", *outputPtr, "sort:", *sortPtr, "limit:", *limitPtr)
+
+	if tuiCmd.NArg() > 0 {
+		tuiCmd.Usage()
+		err = fmt.Errorf("tui takes no arguments")
+		log.Errorln("[tuiSubcmd]", err)
+		return err
+	}
+	if _, err = newResultWriter(*outputPtr, io.Discard, false); err != nil {
+		log.Errorln("[tuiSubcmd]", err)
+		return err
+	}
+
+	db := openStoreReadOnly()
+	defer db.Close()
+
+	opts, err := pickTags(db, store.SearchOptions{Sort: *sortPtr, Limit: *limitPtr})
+	if err == errTUIQuit {
+		log.Infoln("[tuiSubcmd]", err)
+		return err
+	}
+	if err == nil {
+		err = writeList(db, *outPtr, *outputPtr, opts)
+	}
+	if err != nil {
+		log.Errorln("[tuiSubcmd]", err)
+	}
+	return err
+}
+
+// Let the user pick the tags of a search on the terminal, returning the options of the search
+func pickTags(db *store.Store, opts store.SearchOptions) (store.SearchOptions, error) {
+	m, err := newTUIModel(db, opts)
+	if err != nil {
+		return opts, err
+	}
+	term, err := openTerminal()
+	if err != nil {
+		return opts, fmt.Errorf("opening the terminal: %w", err)
+	}
+	resized := make(chan os.Signal, 1)
+	term.notifyResize(resized)
+	err = m.run(term, term.size, resized)
+	if closeErr := term.Close(); err == nil {
+		err = closeErr
+	}
+	return m.searchOptions(), err
+}
+
+// Write the words of a search to a file, or the standard output when filename is empty
+func writeList(db *store.Store, filename, format string, opts store.SearchOptions) error {
+	var w io.Writer = os.Stdout
+	if filename != "" {
+		file, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	out, err := newResultWriter(format, w, false)
+	if err != nil {
+		return err
+	}
+	if err = searchWords(db, opts, out); err != nil {
+		return err
+	}
+	if filename != "" {
+		log.Infof("[tuiSubcmd] wrote %s", filename)
+	}
+	return nil
+}
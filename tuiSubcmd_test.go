@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/proditis/orunmila/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTUISubcmd(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+
+	assert.Equal(t, flag.ErrHelp, tuiSubcmd([]string{"-help"}))
+	assert.EqualError(t, tuiSubcmd([]string{"php"}), "tui takes no arguments")
+	assert.EqualError(t, tuiSubcmd([]string{"-o", "xml"}), `unknown output format "xml", valid formats are plain, json, ndjson, csv, tsv, tagged`)
+}
+
+func TestWriteList(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "php", "index.php", "admin.php"})
+	addSubcmd([]string{"-tags", "api", "swagger.json"})
+
+	db := openStoreReadOnly()
+	defer db.Close()
+	filename := filepath.Join(t.TempDir(), "list.txt")
+	assert.NoError(t, writeList(db, filename, "plain", store.SearchOptions{Query: store.Tag("php"), Sort: store.SortAlpha}))
+	data, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin.php\nindex.php\n", string(data))
+
+	assert.Error(t, writeList(db, filepath.Join(t.TempDir(), "missing", "list.txt"), "plain", store.SearchOptions{}))
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/proditis/orunmila/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("a+\r\x7f\x03\x1b[A\x1bOB\x1b[5~\x1b[6~\x1b[H\x1b[4~\x1b[1;5C\x01é"))
+	var keys []key
+	for {
+		k, err := readKey(r)
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		keys = append(keys, k)
+	}
+	assert.Equal(t, []key{
+		{code: keyRune, r: 'a'}, {code: keyRune, r: '+'}, {code: keyEnter}, {code: keyBackspace}, {code: keyCtrlC},
+		{code: keyUp}, {code: keyDown}, {code: keyPageUp}, {code: keyPageDown}, {code: keyHome}, {code: keyEnd},
+		{code: keyUnknown}, {code: keyUnknown}, {code: keyRune, r: 'é'},
+	}, keys)
+
+	k, err := readKey(bufio.NewReader(strings.NewReader("\x1b")))
+	assert.NoError(t, err)
+	assert.Equal(t, key{code: keyEscape}, k, "a lone ESC is the escape key")
+}
+
+func TestFitWidth(t *testing.T) {
+	assert.Equal(t, "abc  ", fitWidth("abc", 5))
+	assert.Equal(t, "abcd…", fitWidth("abcdefgh", 5))
+	assert.Equal(t, "é…", fitWidth("éèê", 2))
+	assert.Equal(t, "…", fitWidth("abc", 1))
+	assert.Equal(t, "", fitWidth("abc", 0))
+}
+
+// Press the keys of a string, as runes, on a model
+func typeKeys(m *tuiModel, s string) (changed bool) {
+	for _, r := range s {
+		if m.handleKey(key{code: keyRune, r: r}, 10) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func TestTUIModel(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "php,web", "index.php", "admin.php"})
+	addSubcmd([]string{"-tags", "web,api", "swagger.json"})
+	addSubcmd([]string{"-tags", "windows", "web.config"})
+
+	db := openStoreReadOnly()
+	defer db.Close()
+	m, err := newTUIModel(db, store.SearchOptions{Sort: store.SortAlpha})
+	assert.NoError(t, err)
+	assert.Nil(t, m.query())
+	m.refresh()
+	assert.Equal(t, int64(4), m.count)
+
+	// api, php, web, windows
+	assert.True(t, typeKeys(m, "j "), "including php changes the query")
+	assert.Equal(t, "php", m.query().String())
+	assert.True(t, typeKeys(m, "j+"))
+	assert.Equal(t, "(php or web)", m.query().String())
+	m.refresh()
+	assert.Equal(t, int64(3), m.count)
+	assert.Equal(t, []string{"admin.php", "index.php", "swagger.json"}, m.preview)
+
+	assert.True(t, typeKeys(m, "a"))
+	assert.Equal(t, "(php and web)", m.query().String())
+	assert.True(t, typeKeys(m, "kk-"))
+	assert.Equal(t, "((php and web) and not api)", m.query().String())
+	m.refresh()
+	assert.Equal(t, int64(2), m.count)
+
+	assert.True(t, typeKeys(m, "j "), "space cycles php to excluded")
+	assert.Equal(t, "(web and (not api and not php))", m.query().String())
+	assert.True(t, typeKeys(m, " "), "and back to left out")
+	assert.Equal(t, "(web and not api)", m.query().String())
+
+	assert.False(t, typeKeys(m, "/win"))
+	assert.True(t, m.filtering)
+	assert.Len(t, m.visible, 1)
+	assert.False(t, m.handleKey(key{code: keyEnter}, 10))
+	assert.False(t, m.filtering)
+	assert.True(t, typeKeys(m, "x"))
+	assert.Equal(t, "(web and (not api and not windows))", m.query().String())
+	m.handleKey(key{code: keyEscape}, 10)
+	assert.Len(t, m.visible, 4, "escape clears the filter")
+	assert.Equal(t, tuiContinue, m.action)
+
+	assert.False(t, typeKeys(m, "/zzz"))
+	assert.Empty(t, m.visible)
+	assert.False(t, m.handleKey(key{code: keyBackspace}, 10))
+	assert.Equal(t, "zz", m.filter)
+	m.handleKey(key{code: keyEscape}, 10)
+	assert.False(t, m.filtering)
+	assert.Len(t, m.visible, 4)
+
+	m.handleKey(key{code: keyEnd}, 10)
+	assert.Equal(t, 3, m.cursor)
+	m.handleKey(key{code: keyPageUp}, 10)
+	assert.Equal(t, 0, m.cursor)
+
+	assert.True(t, typeKeys(m, "c"))
+	assert.Nil(t, m.query())
+	assert.False(t, typeKeys(m, "c"), "clearing nothing changes nothing")
+
+	m.handleKey(key{code: keyEnter}, 10)
+	assert.Equal(t, tuiWrite, m.action)
+	m.action = tuiContinue
+	typeKeys(m, "q")
+	assert.Equal(t, tuiQuit, m.action)
+}
+
+func TestTUIRender(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "php", "index.php", "admin.php"})
+	addSubcmd([]string{"-tags", "api", "swagger.json"})
+
+	db := openStoreReadOnly()
+	defer db.Close()
+	m, err := newTUIModel(db, store.SearchOptions{})
+	assert.NoError(t, err)
+	typeKeys(m, "j+")
+	m.refresh()
+
+	lines := m.render(60, 10)
+	assert.Len(t, lines, 10)
+	assert.Contains(t, lines[0], "orunmila tui  2 tags")
+	assert.Contains(t, lines[1], "query: php  (included tags match any)")
+	assert.Contains(t, lines[2], "2 words")
+	assert.Contains(t, lines[4], "    api")
+	assert.Contains(t, lines[4], "index.php")
+	assert.Contains(t, lines[5], ansiGreen+ansiReverse+"[+] php")
+	assert.Contains(t, lines[5], "admin.php")
+	assert.Contains(t, lines[9], "space cycle")
+
+	typeKeys(m, "/ph")
+	lines = m.render(60, 10)
+	assert.Contains(t, lines[0], `1 matching "ph"`)
+	assert.Contains(t, lines[9], "filter: ph")
+
+	// the cursor stays on screen
+	m, err = newTUIModel(db, store.SearchOptions{})
+	assert.NoError(t, err)
+	m.handleKey(key{code: keyEnd}, 1)
+	lines = m.render(60, tuiChromeLines+1)
+	assert.Contains(t, lines[4], "php")
+}
+
+// fakeTerminal replays keys and records the screen
+type fakeTerminal struct {
+	io.Reader
+	bytes.Buffer
+}
+
+func (f *fakeTerminal) Write(p []byte) (int, error) {
+	return f.Buffer.Write(p)
+}
+
+func (f *fakeTerminal) Read(p []byte) (int, error) {
+	return f.Reader.Read(p)
+}
+
+func TestTUIRun(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+	addSubcmd([]string{"-tags", "php", "index.php"})
+	addSubcmd([]string{"-tags", "api", "swagger.json"})
+
+	db := openStoreReadOnly()
+	defer db.Close()
+	size := func() (int, int) { return 80, 12 }
+
+	m, err := newTUIModel(db, store.SearchOptions{})
+	assert.NoError(t, err)
+	term := &fakeTerminal{Reader: strings.NewReader("\x1b[B+\r")}
+	assert.NoError(t, m.run(term, size, nil))
+	assert.Equal(t, "php", m.searchOptions().Query.String())
+	assert.True(t, strings.HasPrefix(term.String(), ansiEnter))
+	assert.True(t, strings.HasSuffix(term.String(), ansiLeave))
+	assert.Contains(t, term.String(), "1 words")
+
+	m, err = newTUIModel(db, store.SearchOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, errTUIQuit, m.run(&fakeTerminal{Reader: strings.NewReader("+q")}, size, nil))
+
+	m, err = newTUIModel(db, store.SearchOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, io.EOF, m.run(&fakeTerminal{Reader: strings.NewReader("+")}, size, nil))
+}
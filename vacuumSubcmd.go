@@ -1,7 +1,6 @@
 package main
 
 import (
-	"database/sql"
 	"flag"
 	"fmt"
 
@@ -15,7 +14,7 @@ func vacuumSubcmd(args []string) {
 	vacuumCmd.SetOutput(flag.CommandLine.Output())
 
 	vacuumCmd.Usage = func() {
-		fmt.Fprint(vacuumCmd.Output(), "Rebuild the database file, repacking it into a minimal amount of disk space\n\n")
+		fmt.Fprint(vacuumCmd.Output(), "Apply any pending schema updates and rebuild the database file, repacking it into a minimal amount of disk space\n\n")
 		fmt.Fprintln(vacuumCmd.Output(), "Usage of orunmila vacuum:")
 		fmt.Fprintln(vacuumCmd.Output(), "orunmila [-db <db_path>] [-debug] vacuum")
 		vacuumCmd.PrintDefaults()
@@ -24,12 +23,18 @@ func vacuumSubcmd(args []string) {
 	// nothing to parse, just there to trigger the usage menu
 	vacuumCmd.Parse(args)
 
-	dsn := fmt.Sprintf("file:%s?mode=rw", *dbPtr)
-	db, err := sql.Open("sqlite3", dsn)
+	db := openStore()
+	defer db.Close()
+
+	from, to, err := db.Migrate()
 	check(err)
+	if from != to {
+		log.Printf("database schema upgraded from version %d to %d", from, to)
+	} else {
+		log.Printf("database schema is up to date (version %d)", to)
+	}
 
-	defer db.Close()
-	_, err = db.Query("VACUUM")
+	err = db.Vacuum()
 	check(err)
 
 	log.Println("database rebuilt successfully")
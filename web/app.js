@@ -0,0 +1,237 @@
+// orunmila web UI, a client of the /api endpoints of orunmila serve
+"use strict";
+
+const previewSize = 100;
+const fileExtensions = { plain: "txt", tagged: "txt", ndjson: "ndjson", json: "json", csv: "csv", tsv: "tsv" };
+
+const $ = (id) => document.getElementById(id);
+
+// the state of each tag, "include" or "exclude", keyed by name
+const selected = new Map();
+let tags = [];
+// whether the query field was edited by hand since the tags were last toggled
+let queryEdited = false;
+
+// Call the API, sending the token if one was given
+async function api(path) {
+  const headers = {};
+  const token = localStorage.getItem("orunmila-token");
+  if (token) {
+    headers.Authorization = "Bearer " + token;
+  }
+  const resp = await fetch(path, { headers });
+  if (resp.status === 401) {
+    $("token-form").hidden = false;
+  }
+  if (!resp.ok) {
+    let message = resp.statusText;
+    try {
+      message = (await resp.json()).error;
+    } catch (e) {
+      // not a JSON error
+    }
+    throw new Error(message);
+  }
+  return resp;
+}
+
+function showError(err) {
+  $("error").textContent = err ? err.message : "";
+  $("error").hidden = !err;
+}
+
+// Quote a tag name in a query when it is not a bare word, escaping its quotes and backslashes
+function quoteTag(name) {
+  if (/^[^\s()&|!,"]+$/.test(name) && !/^(and|or|not)$/i.test(name)) {
+    return name;
+  }
+  return '"' + name.replace(/["\\]/g, "\\$&") + '"';
+}
+
+// Build the query of the selected tags
+function buildQuery() {
+  const included = [], excluded = [];
+  for (const [name, state] of selected) {
+    (state === "include" ? included : excluded).push(quoteTag(name));
+  }
+  const terms = [];
+  if (included.length > 1) {
+    terms.push("(" + included.join(" " + $("mode").value + " ") + ")");
+  } else if (included.length === 1) {
+    terms.push(included[0]);
+  }
+  for (const name of excluded) {
+    terms.push("not " + name);
+  }
+  return terms.join(" and ");
+}
+
+// The search parameters of the form, optionally overriding the query
+function searchParams(query) {
+  const params = new URLSearchParams();
+  query = query === undefined ? $("query").value.trim() : query;
+  if (query) {
+    params.set("q", query);
+  }
+  for (const name of ["since", "sort", "limit"]) {
+    const value = $(name).value.trim();
+    if (value && value !== "0") {
+      params.set(name, value);
+    }
+  }
+  return params;
+}
+
+function renderTags() {
+  const filter = $("tag-filter").value.trim().toLowerCase();
+  const body = $("tags").tBodies[0];
+  body.replaceChildren();
+  for (const tag of tags) {
+    if (filter && !tag.name.toLowerCase().includes(filter)) {
+      continue;
+    }
+    const row = body.insertRow();
+    row.className = selected.get(tag.name) || "";
+    row.title = tag.description;
+    row.insertCell().textContent = tag.name;
+    const count = row.insertCell();
+    count.className = "num";
+    count.textContent = tag.words.toLocaleString();
+    row.addEventListener("click", () => toggleTag(tag.name));
+  }
+}
+
+// Cycle a tag through included, excluded and cleared
+function toggleTag(name) {
+  const state = selected.get(name);
+  if (!state) {
+    selected.set(name, "include");
+  } else if (state === "include") {
+    selected.set(name, "exclude");
+  } else {
+    selected.delete(name);
+  }
+  queryEdited = false;
+  $("query").value = buildQuery();
+  renderTags();
+  refresh();
+}
+
+async function loadTags() {
+  tags = await (await api("/api/tags")).json();
+  $("tag-total").textContent = "(" + tags.length + ")";
+  renderTags();
+}
+
+async function loadInfo() {
+  const settings = await (await api("/api/info")).json();
+  const desc = settings.find((s) => s.name === "description");
+  $("description").textContent = desc ? desc.value : "";
+}
+
+async function updateCount(params) {
+  const { words } = await (await api("/api/count?" + params)).json();
+  $("count").textContent = words.toLocaleString() + " words";
+}
+
+async function updatePreview(query) {
+  const params = searchParams(query);
+  const limit = Number(params.get("limit")) || previewSize;
+  params.set("limit", Math.min(limit, previewSize));
+  params.set("st", "");
+  $("preview-query").textContent = query === undefined ? "" : "(" + query + ")";
+  $("preview").textContent = await (await api("/api/search?" + params)).text();
+}
+
+async function updateOverlap() {
+  const body = $("overlap").tBodies[0];
+  body.replaceChildren();
+  const names = [...selected.keys()];
+  if (names.length < 2) {
+    return;
+  }
+  const overlaps = await (await api("/api/overlap?tags=" + encodeURIComponent(names.join(",")))).json();
+  for (const o of overlaps) {
+    const row = body.insertRow();
+    row.insertCell().textContent = o.tag;
+    row.insertCell().textContent = o.other;
+    const count = row.insertCell();
+    count.className = "num";
+    count.textContent = o.words.toLocaleString();
+    row.addEventListener("click", () => updatePreview(quoteTag(o.tag) + " and " + quoteTag(o.other)).catch(showError));
+  }
+}
+
+let refreshTimer;
+
+// Refresh the count, overlap and preview once the form settles
+function refresh() {
+  clearTimeout(refreshTimer);
+  refreshTimer = setTimeout(async () => {
+    try {
+      await Promise.all([updateCount(searchParams()), updateOverlap(), updatePreview()]);
+      showError(null);
+    } catch (err) {
+      $("count").textContent = "";
+      showError(err);
+    }
+  }, 250);
+}
+
+async function download() {
+  try {
+    const params = searchParams();
+    const format = $("format").value;
+    params.set("o", format);
+    const blob = await (await api("/api/search?" + params)).blob();
+    const names = [...selected].filter(([, state]) => state === "include").map(([name]) => name);
+    const link = document.createElement("a");
+    link.href = URL.createObjectURL(blob);
+    link.download = "orunmila" + (names.length ? "-" + names.join("-") : "") + "." + fileExtensions[format];
+    link.click();
+    URL.revokeObjectURL(link.href);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+async function start() {
+  try {
+    await Promise.all([loadTags(), loadInfo()]);
+    showError(null);
+    refresh();
+  } catch (err) {
+    showError(err);
+  }
+}
+
+$("tag-filter").addEventListener("input", renderTags);
+$("mode").addEventListener("change", () => {
+  if (!queryEdited) {
+    $("query").value = buildQuery();
+  }
+  refresh();
+});
+$("query").addEventListener("input", () => {
+  queryEdited = true;
+  refresh();
+});
+for (const name of ["since", "sort", "limit"]) {
+  $(name).addEventListener("input", refresh);
+}
+$("clear").addEventListener("click", () => {
+  selected.clear();
+  queryEdited = false;
+  $("query").value = "";
+  renderTags();
+  refresh();
+});
+$("download").addEventListener("click", download);
+$("token-form").addEventListener("submit", (event) => {
+  event.preventDefault();
+  localStorage.setItem("orunmila-token", $("token").value.trim());
+  $("token-form").hidden = true;
+  start();
+});
+
+start();
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// parse args of the word subcommand and exec the requested action
+func wordSubcmd(args []string) error {
+	wordCmd := flag.NewFlagSet("word", flag.ContinueOnError)
+
+	wordCmd.SetOutput(flag.CommandLine.Output())
+
+	wordCmd.Usage = func() {
+		fmt.Fprint(wordCmd.Output(), "Inspect and remove the words of the database\n\n")
+		fmt.Fprintln(wordCmd.Output(), "Usage of orunmila word:")
+		fmt.Fprintln(wordCmd.Output(), "orunmila [-db <db_path>] [-debug] word <action> [arguments]")
+		fmt.Fprintln(wordCmd.Output(), "\nActions")
+		fmt.Fprintln(wordCmd.Output(), "  show <words>...                 Display the tags, sources and details of words")
+		fmt.Fprintln(wordCmd.Output(), "  untag -tags <tags> <words>...   Remove tags from words, the words are kept")
+		fmt.Fprintln(wordCmd.Output(), "  score [-tags <tags>] <score> <words>...")
+		fmt.Fprintln(wordCmd.Output(), "                                  Set the score of words, or of their associations with tags")
+		fmt.Fprintln(wordCmd.Output(), "  rm <words>...                   Delete words along with their tag associations")
+		fmt.Fprintln(wordCmd.Output(), "  rm -f <filename>...             Delete the words listed in files")
+	}
+
+	return runAction(wordCmd, args, []subcmdAction{
+		{names: []string{"show"}, run: wordShow},
+		{names: []string{"untag"}, run: wordUntag},
+		{names: []string{"score"}, run: wordScore},
+		{names: []string{"rm", "remove", "delete"}, run: wordRm},
+	})
+}
+
+// display the tags and details of words
+func wordShow(args []string) error {
+	cmd := newActionFlagSet("word", "show", "show <words>...", "Display the tags, sources and details of words")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	if cmd.NArg() == 0 {
+		cmd.Usage()
+		return fmt.Errorf("show needs at least one word")
+	}
+
+	db := openStoreReadOnly()
+	defer db.Close()
+
+	for i, word := range cmd.Args() {
+		info, err := db.Word(word)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("[word]: %s\n", info.Name)
+		fmt.Printf("[id]: %d\n", info.ID)
+		fmt.Printf("[tags]: %s\n", strings.Join(info.Tags, ","))
+		fmt.Printf("[created]: %s\n", info.CreatedAt.Format(time.RFC3339))
+		fmt.Printf("[updated]: %s\n", info.UpdatedAt.Format(time.RFC3339))
+		fmt.Printf("[score]: %g\n", info.Score)
+		fmt.Printf("[hits]: %d\n", info.Hits)
+		for _, tag := range info.Tags {
+			if score, ok := info.TagScores[tag]; ok {
+				fmt.Printf("[tag score]: %s %g\n", tag, score)
+			}
+		}
+		for _, src := range info.Sources {
+			fmt.Printf("[source]: %s imported %s", src.Path, src.ImportedAt.Format(time.RFC3339))
+			if src.SHA256 != "" {
+				fmt.Printf(" sha256 %s", src.SHA256)
+			}
+			fmt.Printf(" tags %s\n", strings.Join(src.Tags, ","))
+		}
+	}
+	return nil
+}
+
+// remove tags from words
+func wordUntag(args []string) error {
+	cmd := newActionFlagSet("word", "untag", "untag -tags <tags> <words>...", "Remove tags from words, the words are kept")
+	tagsPtr := cmd.String("tags", "", "a comma separated list of the tags to remove")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	tags := splitList(*tagsPtr)
+	if len(tags) == 0 || cmd.NArg() == 0 {
+		cmd.Usage()
+		return fmt.Errorf("untag needs -tags and at least one word")
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	removed, err := db.UntagWords(cmd.Args(), tags)
+	if err != nil {
+		return err
+	}
+	log.Infof("[wordUntag] removed %d associations", removed)
+	return nil
+}
+
+// set the score of words or of their associations with tags
+func wordScore(args []string) error {
+	cmd := newActionFlagSet("word", "score", "score [-tags <tags>] <score> <words>...", "Set the score of words, or of their associations with tags, used by search -sort score")
+	tagsPtr := cmd.String("tags", "", "a comma separated list of tags, to score the associations of the words with them")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	if cmd.NArg() < 2 {
+		cmd.Usage()
+		return fmt.Errorf("score needs a score and at least one word")
+	}
+	score, err := strconv.ParseFloat(cmd.Arg(0), 64)
+	if err != nil {
+		return fmt.Errorf("invalid score %q", cmd.Arg(0))
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	words, tags := cmd.Args()[1:], splitList(*tagsPtr)
+	updated, err := db.ScoreWords(words, tags, score)
+	if err != nil {
+		return err
+	}
+	if len(tags) > 0 {
+		log.Infof("[wordScore] scored %d associations", updated)
+	} else {
+		log.Infof("[wordScore] scored %d words", updated)
+	}
+	return nil
+}
+
+// delete words along with their associations
+func wordRm(args []string) error {
+	cmd := newActionFlagSet("word", "rm", "rm [-f] <words or filenames>...", "Delete words along with their tag associations")
+	filesPtr := cmd.Bool("f", false, "read the words to delete from the given files, one per line, - reads from the standard input")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	if cmd.NArg() == 0 {
+		cmd.Usage()
+		return fmt.Errorf("rm needs at least one word or filename")
+	}
+
+	db := openStore()
+	defer db.Close()
+
+	if !*filesPtr {
+		removed, err := db.RemoveWords(cmd.Args())
+		if err != nil {
+			return err
+		}
+		log.Infof("[wordRm] removed %d words", removed)
+		return nil
+	}
+
+	for _, filename := range cmd.Args() {
+		if filename != "-" && !isFileExists(filename) {
+			return fmt.Errorf("%q does not exists", filename)
+		}
+		file, err := openInput(filename)
+		if err != nil {
+			return err
+		}
+		removed, err := db.RemoveReader(file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+		log.Infof("[wordRm] removed %d words listed in %s", removed, filename)
+	}
+	return nil
+}
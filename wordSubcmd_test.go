@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/proditis/orunmila/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWordSubcmdActions(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+
+	importSubcmd([]string{"-tags", "lista,all", "lista.txt"})
+	addSubcmd([]string{"-tags", "cli", "word1", "word2"})
+
+	assert.NoError(t, wordSubcmd([]string{"show", "common1", "word1"}))
+	assert.Error(t, wordSubcmd([]string{"show", "missing"}))
+	assert.NoError(t, wordSubcmd([]string{"untag", "-tags", "all", "unique1", "unique2"}))
+	assert.Error(t, wordSubcmd([]string{"untag", "unique1"}))
+	assert.NoError(t, wordSubcmd([]string{"rm", "word1", "word2"}))
+	assert.NoError(t, wordSubcmd([]string{"rm", "-f", "lista.txt"}))
+	assert.Error(t, wordSubcmd([]string{"rm", "-f", "missing.txt"}))
+
+	db := openStore()
+	defer db.Close()
+	var words, links int64
+	assert.NoError(t, db.DB().QueryRow("select count(*) from words").Scan(&words))
+	assert.NoError(t, db.DB().QueryRow("select count(*) from wt").Scan(&links))
+	assert.Equal(t, int64(0), words)
+	assert.Equal(t, int64(0), links)
+}
+
+func TestWordSubcmdScore(t *testing.T) {
+	createDbFileifNotExists(*dbPtr)
+	defer os.Remove(*dbPtr)
+
+	addSubcmd([]string{"-tags", "php", "admin", "login", "index"})
+	assert.NoError(t, wordSubcmd([]string{"score", "10", "index"}))
+	assert.NoError(t, wordSubcmd([]string{"score", "-tags", "php", "5", "login"}))
+	assert.EqualError(t, wordSubcmd([]string{"score", "high", "index"}), `invalid score "high"`)
+	assert.Error(t, wordSubcmd([]string{"score", "10"}))
+
+	db := openStoreReadOnly()
+	defer db.Close()
+	var out bytes.Buffer
+	next, _ := newResultWriter("plain", &out, false)
+	assert.NoError(t, searchWords(db, store.SearchOptions{Query: store.TagsQuery([]string{"php"}), Sort: store.SortScore, Limit: 2}, next))
+	assert.Equal(t, "index\nlogin\n", out.String())
+
+	assert.NoError(t, searchSubcmd([]string{"-tags", "php", "-sort", "length", "-limit", "1"}))
+	assert.Error(t, searchSubcmd([]string{"-tags", "php", "-sort", "random"}))
+}